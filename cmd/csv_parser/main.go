@@ -13,7 +13,6 @@ import (
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
-	"github.com/blevesearch/bleve/v2"
 	"github.com/jmoiron/sqlx"
 	"modernc.org/sqlite"
 	sqlite3 "modernc.org/sqlite/lib"
@@ -56,7 +55,7 @@ func newAlwaysAssignedTagRepository(db *sqlx.DB, tagRepository *repository.TagRe
 }
 
 func (repository *alwaysAssignedTagRepository) Create(request models.CreateTagRequest) (response models.TagResponse, err error) {
-	res, err := repository.db.Exec("INSERT INTO tags VALUES (NULL, ?, true)", request.Name)
+	res, err := repository.db.Exec("INSERT INTO tags (name, assigned) VALUES (?, true)", request.Name)
 	if err != nil {
 		return response, err
 	}
@@ -72,12 +71,18 @@ func (repository *alwaysAssignedTagRepository) Create(request models.CreateTagRe
 	}, nil
 }
 
+func (repository *alwaysAssignedTagRepository) Resolve(tx *sqlx.Tx, tags []models.TagResponse) (response []models.TagResponse, err error) {
+	return repository.tagRepository.Resolve(tx, tags)
+}
 func (repository *alwaysAssignedTagRepository) AssignForDocument(tx *sqlx.Tx, documentID models.ID, tags []models.TagResponse) (err error) {
 	return repository.tagRepository.AssignForDocument(tx, documentID, tags)
 }
 func (repository *alwaysAssignedTagRepository) ListForDocument(tx *sqlx.Tx, documentID models.ID) (response []models.TagResponse, err error) {
 	return repository.tagRepository.ListForDocument(tx, documentID)
 }
+func (repository *alwaysAssignedTagRepository) ListForDocuments(tx *sqlx.Tx, documentIDs []models.ID) (response map[models.ID][]models.TagResponse, err error) {
+	return repository.tagRepository.ListForDocuments(tx, documentIDs)
+}
 func (repository *alwaysAssignedTagRepository) DeleteForDocument(tx *sqlx.Tx, documentID models.ID, tags []models.TagResponse) (err error) {
 	return repository.tagRepository.DeleteForDocument(tx, documentID, tags)
 }
@@ -87,6 +92,9 @@ func (repository *alwaysAssignedTagRepository) List() (response []models.TagResp
 func (repository *alwaysAssignedTagRepository) ReadManyByNames(names []string) (response []models.TagResponse, err error) {
 	return repository.tagRepository.ReadManyByNames(names)
 }
+func (repository *alwaysAssignedTagRepository) ResolveAliases(names []string) (response map[string]models.TagResponse, err error) {
+	return repository.tagRepository.ResolveAliases(names)
+}
 
 func loadTagsInDb(tagRepo tagCreator, tagNames []string) map[string]models.TagResponse {
 	result := make(map[string]models.TagResponse)
@@ -238,7 +246,7 @@ func main() {
 
 	alwaysAssignedtagRepository := newAlwaysAssignedTagRepository(db, repository.NewTagRepository(db))
 	documentRepository := repository.NewDocumentRepository(db, alwaysAssignedtagRepository)
-	index, err := bleve.New("test_index.bleve", service.GetIndexMapping())
+	index, err := service.NewIndex("test_index.bleve")
 	if err != nil {
 		panic(err)
 	}
@@ -254,5 +262,9 @@ func main() {
 	fmt.Println("successfully added to db")
 	fmt.Println("starting to index...")
 	indexDocuments(service.NewIndexService(index, documentRepository, alwaysAssignedtagRepository), dbDocuments)
+	// Documents are indexed directly so outbox entries written on their creation are not needed
+	if _, err := db.Exec("DELETE FROM index_outbox"); err != nil {
+		panic(err)
+	}
 	fmt.Println("FINISHED!!!")
 }
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 
-	"github.com/Wayodeni/tagsearch-backend/internal/config"
+	appConfig "github.com/Wayodeni/tagsearch-backend/internal/config"
 	"github.com/Wayodeni/tagsearch-backend/internal/router"
 	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/service/outbox"
+	"github.com/Wayodeni/tagsearch-backend/internal/service/trash"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
 	"github.com/blevesearch/bleve/v2"
@@ -14,17 +18,17 @@ import (
 )
 
 func main() {
-	config, err := config.NewConfig()
+	config, err := appConfig.NewConfig()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Println(config)
 
-	db := db.NewDb(config.Db.Path)
+	db := db.Connect(config.Db.Driver, config.Db.Path, config.Db.DSN)
 
 	index, err := bleve.Open(config.Index.Path)
 	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
-		index, err = bleve.New(config.Index.Path, service.GetIndexMapping())
+		index, err = service.NewIndex(config.Index.Path)
 		if err != nil {
 			panic(err)
 		}
@@ -32,11 +36,46 @@ func main() {
 		panic(err)
 	}
 
+	mappingErr := service.CheckMappingHash(index)
+	if mappingErr != nil && !errors.Is(mappingErr, service.ErrMappingChanged) {
+		panic(mappingErr)
+	} else if mappingErr != nil && config.Index.OnMappingChange == appConfig.OnMappingChangeFail {
+		log.Fatalf("%s. Start with -onmappingchange=rebuild to rebuild index in background", mappingErr)
+	}
+
 	tagRepository := repository.NewTagRepository(db)
 	documentRepository := repository.NewDocumentRepository(db, tagRepository)
 	indexService := service.NewIndexService(index, documentRepository, tagRepository)
+	indexService.MaxPageSize = config.Search.MaxPageSize
+	if config.Search.RelevanceProfilesPath != "" {
+		if indexService.RelevanceProfiles, err = service.LoadRelevanceProfiles(config.Search.RelevanceProfilesPath); err != nil {
+			panic(err)
+		}
+	}
+	if config.Search.JudgedQueriesPath != "" {
+		if indexService.JudgedQueries, err = service.LoadJudgedQueries(config.Search.JudgedQueriesPath); err != nil {
+			panic(err)
+		}
+	}
+
+	outboxRepository := repository.NewOutboxRepository(db)
+	indexWorker := outbox.NewWorker(outboxRepository, documentRepository, indexService)
+	go indexWorker.Run(context.Background())
+
+	trashRepository := repository.NewTrashRepository(db)
+	if config.Trash.Retention > 0 {
+		go trash.NewPurger(trashRepository, config.Trash.Retention).Run(context.Background())
+	}
+
+	// Old index keeps serving requests until rebuilt one replaces it
+	if mappingErr != nil {
+		log.Println(mappingErr, "- rebuilding index in background")
+		if err := indexService.StartRebuild(documentRepository, config.Index.Path); err != nil {
+			panic(err)
+		}
+	}
 
-	router := router.NewRouter(tagRepository, documentRepository, indexService)
+	router := router.NewRouter(tagRepository, documentRepository, trashRepository, outboxRepository, indexService, indexWorker, config.Index.Path)
 
 	if config.App.EnableProfiling {
 		pprof.Register(router)
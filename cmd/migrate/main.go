@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/config"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
+)
+
+const usage = "usage: migrate [flags] up|down|status"
+
+/*
+Applies or reverts database schema migrations.
+  - up     applies all pending migrations
+  - down   reverts last applied migrations, -steps of them
+  - status prints all known migrations and time they were applied at
+*/
+func main() {
+	steps := flag.Int("steps", 1, "number of migrations to revert with down")
+
+	config, err := config.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	if flag.NArg() != 1 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	database := db.OpenDriver(config.Db.Driver, config.Db.Path, config.Db.DSN)
+	defer database.Close()
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := db.MigrateUp(database)
+		if err != nil {
+			panic(err)
+		}
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		reverted, err := db.MigrateDown(database, *steps)
+		if err != nil {
+			panic(err)
+		}
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := db.Status(database)
+		if err != nil {
+			panic(err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
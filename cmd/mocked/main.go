@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/Wayodeni/tagsearch-backend/internal/router"
+	"github.com/Wayodeni/tagsearch-backend/internal/service/outbox"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
 	utilities "github.com/Wayodeni/tagsearch-backend/internal/tests/index"
@@ -14,6 +15,7 @@ func main() {
 
 	testIndexService, _, _ := utilities.NewTestIndexService("../../internal/tests/index/lenta-ru-news.csv")
 
-	r := router.NewRouter(tagRepository, documentRepository, testIndexService)
+	outboxRepository := repository.NewOutboxRepository(db)
+	r := router.NewRouter(tagRepository, documentRepository, repository.NewTrashRepository(db), outboxRepository, testIndexService, outbox.NewWorker(outboxRepository, documentRepository, testIndexService), "")
 	r.Run()
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/config"
+	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+)
+
+const statusPollInterval = time.Second
+
+/*
+Starts full index rebuild on running server and waits until it is finished.
+Index is rebuilt by server itself because index can be opened by one process only
+and server keeps answering requests from old index until new one is ready.
+*/
+func main() {
+	serverURL := flag.String("url", "", "url of running server, built from host and port if empty")
+
+	config, err := config.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	if *serverURL == "" {
+		host := config.App.Host
+		if host == "0.0.0.0" {
+			host = "localhost"
+		}
+		*serverURL = fmt.Sprintf("http://%s:%s", host, config.App.Port)
+	}
+	reindexURL := *serverURL + "/api/v1/admin/reindex"
+
+	response, err := http.Post(reindexURL, "application/json", nil)
+	if err != nil {
+		panic(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		fmt.Printf("unable to start rebuild: server responded with '%s'\n", response.Status)
+		os.Exit(1)
+	}
+
+	for {
+		time.Sleep(statusPollInterval)
+
+		status, err := getStatus(reindexURL)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("\rindexed %d/%d documents", status.IndexedDocuments, status.TotalDocuments)
+		if status.Running {
+			continue
+		}
+
+		fmt.Println()
+		if status.Error != "" {
+			fmt.Println("rebuild failed:", status.Error)
+			os.Exit(1)
+		}
+		fmt.Printf("rebuild finished in %s\n", status.FinishedAt.Sub(*status.StartedAt).Round(time.Second))
+		return
+	}
+}
+
+func getStatus(reindexURL string) (status service.RebuildStatus, err error) {
+	response, err := http.Get(reindexURL)
+	if err != nil {
+		return status, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("server responded with '%s'", response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&status)
+	return status, err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/config"
+	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
+	"github.com/blevesearch/bleve/v2"
+)
+
+/*
+Compares documents in database and index and prints JSON report.
+Index can be opened by one process only, so server must be stopped,
+use /api/v1/admin/verify endpoint to check index of running server.
+Exits with code 1 if inconsistencies were found and not repaired.
+*/
+func main() {
+	repair := flag.Bool("repair", false, "reindex missing and stale documents and delete orphaned ones")
+
+	config, err := config.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	db := db.Connect(config.Db.Driver, config.Db.Path, config.Db.DSN)
+	defer db.Close()
+
+	index, err := bleve.Open(config.Index.Path)
+	if err != nil {
+		panic(err)
+	}
+	defer index.Close()
+
+	tagRepository := repository.NewTagRepository(db)
+	documentRepository := repository.NewDocumentRepository(db, tagRepository)
+	indexService := service.NewIndexService(index, documentRepository, tagRepository)
+
+	report, err := indexService.Verify(documentRepository, *repair)
+	if err != nil {
+		panic(err)
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(output))
+
+	if !report.Consistent() && !report.Repaired {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	indexService       *service.IndexService
+	documentRepository service.DocumentIDLister
+	outboxRepository   OutboxStorage
+	indexNotifier      IndexNotifier
+	indexPath          string
+}
+
+func NewAdminController(indexService *service.IndexService, documentRepository service.DocumentIDLister, outboxRepository OutboxStorage, indexNotifier IndexNotifier, indexPath string) *AdminController {
+	return &AdminController{
+		indexService:       indexService,
+		documentRepository: documentRepository,
+		outboxRepository:   outboxRepository,
+		indexNotifier:      indexNotifier,
+		indexPath:          indexPath,
+	}
+}
+
+// Verification report with index outbox entries which are not retried anymore
+type VerifyResponse struct {
+	service.VerifyReport
+	DeadOutboxEntries []models.OutboxEntry `json:"deadOutboxEntries"`
+}
+
+// Reports documents which differ between database and index
+func (controller *AdminController) Verify(c *gin.Context) {
+	controller.verify(c, false)
+}
+
+// Reports documents which differ between database and index, brings index in line with database and retries dead outbox entries
+func (controller *AdminController) Repair(c *gin.Context) {
+	controller.verify(c, true)
+}
+
+func (controller *AdminController) verify(c *gin.Context, repair bool) {
+	report, err := controller.indexService.Verify(controller.documentRepository, repair)
+	if err != nil {
+		err = fmt.Errorf("unable to verify index: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := VerifyResponse{VerifyReport: report, DeadOutboxEntries: []models.OutboxEntry{}}
+	deadEntries, err := controller.outboxRepository.ListDead()
+	if err != nil {
+		err = fmt.Errorf("unable to list dead index outbox entries: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.DeadOutboxEntries = append(response.DeadOutboxEntries, deadEntries...)
+
+	if repair && len(deadEntries) > 0 {
+		if err := controller.outboxRepository.Revive(); err != nil {
+			err = fmt.Errorf("unable to revive dead index outbox entries: %w", err)
+			log.Println(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		controller.indexNotifier.Notify()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Starts full index rebuild in background, progress is reported by ReindexStatus
+func (controller *AdminController) Reindex(c *gin.Context) {
+	err := controller.indexService.StartRebuild(controller.documentRepository, controller.indexPath)
+	if errors.Is(err, service.ErrRebuildInProgress) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to start index rebuild: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, controller.indexService.RebuildStatus())
+}
+
+func (controller *AdminController) ReindexStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.indexService.RebuildStatus())
+}
+
+// Evaluates ranking of relevance profile against judged queries
+func (controller *AdminController) EvaluateRelevance(c *gin.Context) {
+	var evaluationRequest service.EvaluationRequest
+	if err := c.ShouldBindQuery(&evaluationRequest); err != nil {
+		err = fmt.Errorf("unable to bind evaluation request: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	evaluation, err := controller.indexService.EvaluateRelevance(&evaluationRequest)
+	if errors.Is(err, service.ErrInvalidEvaluationRequest) || errors.Is(err, service.ErrUnknownRelevanceProfile) {
+		err = fmt.Errorf("error during relevance evaluation: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("error during relevance evaluation: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	// Bulk requests with more operations are rejected
+	MaxBulkOperations = 10000
+	// Bulk requests with bigger body are rejected
+	MaxBulkRequestSize = 64 << 20
+	// Maximum length of NDJSON line, i.e. of single operation
+	maxBulkLineSize = 16 << 20
+
+	ndjsonContentType = "application/x-ndjson"
+)
+
+var (
+	errTooManyBulkOperations = fmt.Errorf("bulk request must not contain more than %d operations", MaxBulkOperations)
+)
+
+/*
+Executes create, update and delete operations passed either as JSON array or as NDJSON with operation per line.
+Operations are independent: invalid or failed operation does not prevent others, its status and error are returned in its item of response.
+*/
+func (controller *DocumentController) Bulk(c *gin.Context) {
+	// Body is read no further than limits, so oversized request is rejected before it is read whole
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, MaxBulkRequestSize)
+	var rawOperations []json.RawMessage
+	var err error
+	if c.ContentType() == ndjsonContentType {
+		rawOperations, err = readNDJSON(body)
+	} else {
+		rawOperations, err = readJSONArray(body)
+	}
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		err = fmt.Errorf("bulk request body must not be larger than %d bytes", MaxBulkRequestSize)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to read bulk request: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(rawOperations) == 0 {
+		err = errors.New("bulk request must contain at least one operation")
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := models.BulkResponse{Items: make([]models.BulkItemResponse, len(rawOperations))}
+	author := getAuthor(c)
+
+	// Valid operations are executed, invalid ones are reported immediately
+	operations := make([]models.BulkOperation, 0, len(rawOperations))
+	positions := make([]int, 0, len(rawOperations))
+	for i, rawOperation := range rawOperations {
+		operation, err := decodeBulkOperation(rawOperation)
+		response.Items[i] = models.BulkItemResponse{Op: operation.Op, ID: operation.ID}
+		if err != nil {
+			response.Items[i].Status = http.StatusBadRequest
+			response.Items[i].Error = err.Error()
+			response.Errors = true
+			continue
+		}
+
+		if operation.Create != nil {
+			operation.Create.Author = author
+		}
+		if operation.Update != nil {
+			operation.Update.Author = author
+		}
+		operations = append(operations, operation)
+		positions = append(positions, i)
+	}
+
+	results, err := controller.repository.Bulk(operations)
+	if err != nil {
+		log.Println(fmt.Errorf("bulk request is executed partially: %w", err))
+	}
+
+	executed := false
+	for j, result := range results {
+		item := &response.Items[positions[j]]
+		item.ID = result.ID
+		item.Status = bulkStatus(item.Op, result.Err)
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+			response.Errors = true
+		} else {
+			executed = true
+		}
+	}
+
+	if executed {
+		controller.indexNotifier.Notify()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Returns raw operations from non-empty lines of NDJSON body, reading stops once there are too many operations
+func readNDJSON(body io.Reader) (rawOperations []json.RawMessage, err error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxBulkLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rawOperations) == MaxBulkOperations {
+			return nil, errTooManyBulkOperations
+		}
+		rawOperations = append(rawOperations, json.RawMessage(bytes.Clone(line)))
+	}
+	return rawOperations, scanner.Err()
+}
+
+// Returns raw operations from elements of JSON array body, reading stops once there are too many operations
+func readJSONArray(body io.Reader) (rawOperations []json.RawMessage, err error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('[') {
+		return nil, errors.New("bulk request must be JSON array of operations")
+	}
+
+	for decoder.More() {
+		if len(rawOperations) == MaxBulkOperations {
+			return nil, errTooManyBulkOperations
+		}
+		var rawOperation json.RawMessage
+		if err := decoder.Decode(&rawOperation); err != nil {
+			return nil, err
+		}
+		rawOperations = append(rawOperations, rawOperation)
+	}
+
+	// Closing bracket is read to report truncated array
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return rawOperations, nil
+}
+
+// Decodes and validates operation the same way as request of single document endpoint
+func decodeBulkOperation(rawOperation json.RawMessage) (operation models.BulkOperation, err error) {
+	var request models.BulkOperationRequest
+	if err := json.Unmarshal(rawOperation, &request); err != nil {
+		return operation, fmt.Errorf("unable to decode operation: %w", err)
+	}
+
+	if operation, err = request.Decode(); err != nil {
+		return operation, err
+	}
+
+	if operation.Create != nil {
+		err = binding.Validator.ValidateStruct(operation.Create)
+	} else if operation.Update != nil {
+		err = binding.Validator.ValidateStruct(operation.Update)
+	}
+	return operation, err
+}
+
+// Returns HTTP status of bulk operation as if it was executed by single document endpoint
+func bulkStatus(op string, err error) int {
+	switch {
+	case err == nil && op == models.BulkOperationCreate:
+		return http.StatusCreated
+	case err == nil && op == models.BulkOperationDelete:
+		return http.StatusNoContent
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, repository.ErrDocumentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrTagNotFound), errors.Is(err, repository.ErrUnsupportedLanguage):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
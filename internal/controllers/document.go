@@ -1,26 +1,30 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
-	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
 	"github.com/gin-gonic/gin"
+	"gopkg.in/guregu/null.v4"
 )
 
+// Header with name of user who changes document. It is saved in document revisions.
+const authorHeader = "X-Author"
+
 type DocumentController struct {
-	repository   *repository.DocumentRepository
-	indexService *service.IndexService
+	repository    DocumentStorage
+	indexNotifier IndexNotifier
 }
 
-func NewDocumentController(documentRepository *repository.DocumentRepository, indexService *service.IndexService) *DocumentController {
+func NewDocumentController(documentRepository DocumentStorage, indexNotifier IndexNotifier) *DocumentController {
 	return &DocumentController{
-		repository:   documentRepository,
-		indexService: indexService,
+		repository:    documentRepository,
+		indexNotifier: indexNotifier,
 	}
 }
 
@@ -34,20 +38,23 @@ func (controller *DocumentController) Create(c *gin.Context) {
 		return
 	}
 
+	createDocumentRequest.Author = getAuthor(c)
+
 	createdDocument, err := controller.repository.Create(createDocumentRequest)
-	if err != nil {
+	if errors.Is(err, repository.ErrTagNotFound) || errors.Is(err, repository.ErrUnsupportedLanguage) {
 		err = fmt.Errorf("unable to create document in storage: %w", err)
 		log.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
 		return
-	}
-
-	if err := controller.indexService.Index([]models.DocumentResponse{createdDocument}); err != nil {
-		err = fmt.Errorf("unable to index document after creation: %w", err)
+	} else if err != nil {
+		err = fmt.Errorf("unable to create document in storage: %w", err)
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
 	}
 
+	controller.indexNotifier.Notify()
+
 	c.JSON(http.StatusCreated, createdDocument)
 }
 
@@ -89,22 +96,28 @@ func (controller *DocumentController) Update(c *gin.Context) {
 	}
 
 	updateDocumentRequest.RemoveCommonTags()
+	updateDocumentRequest.Author = getAuthor(c)
 
 	documentResponse, err := controller.repository.Update(int64(id), updateDocumentRequest)
-	if err != nil {
+	if errors.Is(err, repository.ErrDocumentNotFound) {
 		err = fmt.Errorf("unable to update document: %w", err)
 		log.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
 		return
-	}
-
-	if err := controller.indexService.Index([]models.DocumentResponse{documentResponse}); err != nil {
-		err = fmt.Errorf("unable to update document in index: %w", err)
+	} else if errors.Is(err, repository.ErrTagNotFound) || errors.Is(err, repository.ErrUnsupportedLanguage) {
+		err = fmt.Errorf("unable to update document: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to update document: %w", err)
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	controller.indexNotifier.Notify()
+
 	c.JSON(http.StatusOK, documentResponse)
 }
 
@@ -124,12 +137,7 @@ func (controller *DocumentController) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := controller.indexService.Delete([]models.ID{int64(id)}); err != nil {
-		err = fmt.Errorf("unable to delete document from index: %w", err)
-		log.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
-		return
-	}
+	controller.indexNotifier.Notify()
 
 	c.Status(http.StatusNoContent)
 }
@@ -158,8 +166,44 @@ func (controller *DocumentController) List(c *gin.Context) {
 		return
 	}
 
-	response, err := controller.repository.List()
+	var listDocumentsRequest models.ListDocumentsRequest
+	if err := c.ShouldBindQuery(&listDocumentsRequest); err != nil {
+		err = fmt.Errorf("unable to bind query params during documents list: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := controller.repository.ListPage(listDocumentsRequest)
+	if errors.Is(err, repository.ErrInvalidListRequest) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *DocumentController) ListRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
+		err = fmt.Errorf("unable to convert id '%s' into int in document revisions list", c.Param("id"))
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := controller.repository.ListRevisions(int64(id))
+	if errors.Is(err, repository.ErrDocumentNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to list revisions of document with id '%v': %w", id, err)
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
@@ -167,3 +211,99 @@ func (controller *DocumentController) List(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// Returns diff between revisions passed in `from` and `to` query params
+func (controller *DocumentController) DiffRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		err = fmt.Errorf("unable to convert id '%s' into int in document revisions diff", c.Param("id"))
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	from, err := strconv.ParseInt(c.Query("from"), 10, 64)
+	if err != nil {
+		err = fmt.Errorf("unable to convert revision 'from' into int: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := strconv.ParseInt(c.Query("to"), 10, 64)
+	if err != nil {
+		err = fmt.Errorf("unable to convert revision 'to' into int: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revisions := make([]models.DocumentRevision, 0, 2)
+	for _, revision := range []int64{from, to} {
+		documentRevision, err := controller.repository.ReadRevision(int64(id), revision)
+		if errors.Is(err, repository.ErrRevisionNotFound) {
+			log.Println(err)
+			c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			err = fmt.Errorf("unable to read revision %d of document with id '%v': %w", revision, id, err)
+			log.Println(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		revisions = append(revisions, documentRevision)
+	}
+
+	diff, err := revisions[0].Diff(revisions[1])
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (controller *DocumentController) RestoreRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		err = fmt.Errorf("unable to convert id '%s' into int in document restore", c.Param("id"))
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revision, err := strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil {
+		err = fmt.Errorf("unable to convert revision '%s' into int in document restore", c.Param("rev"))
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	documentResponse, err := controller.repository.Restore(int64(id), revision, getAuthor(c))
+	if errors.Is(err, repository.ErrRevisionNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrTagNotFound) {
+		err = fmt.Errorf("unable to restore document: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to restore document: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	controller.indexNotifier.Notify()
+
+	c.JSON(http.StatusOK, documentResponse)
+}
+
+// Author is unknown if header is absent or empty
+func getAuthor(c *gin.Context) null.String {
+	author := c.GetHeader(authorHeader)
+	return null.NewString(author, author != "")
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
 	"github.com/gin-gonic/gin"
@@ -25,7 +27,7 @@ func (controller *SearchController) Search(c *gin.Context) {
 	queryString := c.Query("query")
 
 	pageSizeString, ok := c.GetQuery("pageSize")
-	pageSizeInt := 10
+	pageSizeInt := service.DefaultPageSize
 	var err error
 	if ok {
 		pageSizeInt, err = strconv.Atoi(pageSizeString)
@@ -51,14 +53,102 @@ func (controller *SearchController) Search(c *gin.Context) {
 	if pageNumberInt > 0 {
 		pageNumberInt -= 1 // substituting because frontend does not have 0 in paginator
 	}
-	searchResults, err := controller.service.Find(&service.SearchDocumentRequest{
-		Query:      queryString,
-		Tags:       c.QueryArray("tags[]"),
-		PageSize:   pageSizeInt,
-		PageNumber: pageNumberInt,
-	})
 
-	if err != nil && strings.Contains(err.Error(), "parse error") {
+	highlightRequest, err := getHighlightRequest(c)
+	if err != nil {
+		err = fmt.Errorf("error during search: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	searchRequest := service.SearchDocumentRequest{
+		Query:         queryString,
+		Tags:          c.QueryArray("tags[]"),
+		TagFilter:     c.Query("tagFilter"),
+		PageSize:      pageSizeInt,
+		PageNumber:    pageNumberInt,
+		Sort:          getListParam(c, "sort"),
+		Cursor:        c.Query("cursor"),
+		Highlight:     highlightRequest,
+		DateHistogram: getDateHistogramRequest(c),
+		Operator:      c.Query("operator"),
+		Fields:        getListParam(c, "fields"),
+		Profile:       c.Query("profile"),
+	}
+
+	boolParams := []struct {
+		name  string
+		value *bool
+	}{
+		{name: "includeDescendants", value: &searchRequest.IncludeDescendants},
+		{name: "autoCorrect", value: &searchRequest.AutoCorrect},
+		{name: "phrase", value: &searchRequest.Phrase},
+	}
+	for _, boolParam := range boolParams {
+		if value, ok := c.GetQuery(boolParam.name); ok {
+			if *boolParam.value, err = strconv.ParseBool(value); err != nil {
+				err = fmt.Errorf("error during search: unable to parse %s: %w", boolParam.name, err)
+				log.Println(err)
+				c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	intParams := []struct {
+		name  string
+		value *int
+	}{
+		{name: "fuzziness", value: &searchRequest.Fuzziness},
+		{name: "slop", value: &searchRequest.Slop},
+	}
+	for _, intParam := range intParams {
+		if value, ok := c.GetQuery(intParam.name); ok {
+			if *intParam.value, err = strconv.Atoi(value); err != nil {
+				err = fmt.Errorf("error during search: unable to parse %s: %w", intParam.name, err)
+				log.Println(err)
+				c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	dateParams := []struct {
+		name       string
+		value      *time.Time
+		endOfRange bool
+	}{
+		{name: "createdFrom", value: &searchRequest.CreatedFrom},
+		{name: "createdTo", value: &searchRequest.CreatedTo, endOfRange: true},
+		{name: "updatedFrom", value: &searchRequest.UpdatedFrom},
+		{name: "updatedTo", value: &searchRequest.UpdatedTo, endOfRange: true},
+	}
+	for _, dateParam := range dateParams {
+		if *dateParam.value, err = getDateParam(c, dateParam.name, dateParam.endOfRange); err != nil {
+			err = fmt.Errorf("error during search: %w", err)
+			log.Println(err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	searchResults, err := controller.service.Find(&searchRequest)
+
+	if errors.Is(err, service.ErrInvalidHighlightRequest) ||
+		errors.Is(err, service.ErrInvalidSort) ||
+		errors.Is(err, service.ErrInvalidCursor) ||
+		errors.Is(err, service.ErrInvalidPagination) ||
+		errors.Is(err, service.ErrInvalidQueryOptions) ||
+		errors.Is(err, service.ErrUnknownRelevanceProfile) ||
+		errors.Is(err, service.ErrInvalidTagFilter) ||
+		errors.Is(err, service.ErrInvalidDateRange) ||
+		errors.Is(err, service.ErrInvalidDateHistogram) {
+		err = fmt.Errorf("error during search: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil && strings.Contains(err.Error(), "parse error") {
 		err = fmt.Errorf("error during querystring parsing: %w", err)
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
@@ -72,3 +162,115 @@ func (controller *SearchController) Search(c *gin.Context) {
 
 	c.JSON(http.StatusOK, searchResults)
 }
+
+func (controller *SearchController) Suggest(c *gin.Context) {
+	var suggestRequest service.SuggestRequest
+	if err := c.ShouldBindQuery(&suggestRequest); err != nil {
+		err = fmt.Errorf("unable to bind suggest request: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	suggestions, err := controller.service.Suggest(&suggestRequest)
+	if errors.Is(err, service.ErrInvalidSuggestRequest) {
+		err = fmt.Errorf("error during suggest: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("error during suggest: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+/*
+Parses highlight options from query params. Highlighting is enabled only with `highlight=true`,
+otherwise nil request is returned.
+*/
+func getHighlightRequest(c *gin.Context) (*service.HighlightRequest, error) {
+	highlightString, ok := c.GetQuery("highlight")
+	if !ok {
+		return nil, nil
+	}
+	highlight, err := strconv.ParseBool(highlightString)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse highlight flag: %w", err)
+	}
+	if !highlight {
+		return nil, nil
+	}
+
+	request := service.HighlightRequest{
+		Style: c.Query("highlightStyle"),
+	}
+
+	if fragmentSizeString, ok := c.GetQuery("fragmentSize"); ok {
+		request.FragmentSize, err = strconv.Atoi(fragmentSizeString)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse fragment size: %w", err)
+		}
+	}
+
+	if fragmentsString, ok := c.GetQuery("fragments"); ok {
+		request.Fragments, err = strconv.Atoi(fragmentsString)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse fragments quantity: %w", err)
+		}
+	}
+
+	return &request, nil
+}
+
+// Collects values passed both as comma separated list and as repeated query params, e.g. sort keys
+func getListParam(c *gin.Context, name string) (values []string) {
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+/*
+Parses date query param which may be passed either as RFC 3339 timestamp or as date `2006-01-02`.
+Date without time means start of the day in UTC, or its end if endOfRange is true,
+so `createdTo=2024-01-31` includes documents created during the whole day. Zero time is returned if param is absent.
+*/
+func getDateParam(c *gin.Context, name string, endOfRange bool) (time.Time, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse %s '%s', expected RFC 3339 timestamp or date YYYY-MM-DD", name, value)
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// Date histogram is built only if `histogram` interval is passed, otherwise nil request is returned
+func getDateHistogramRequest(c *gin.Context) *service.DateHistogramRequest {
+	interval, ok := c.GetQuery("histogram")
+	if !ok {
+		return nil
+	}
+	return &service.DateHistogramRequest{
+		Field:    c.Query("histogramField"),
+		Interval: interval,
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Storage of tags used by TagController. Implemented by repository.TagRepository for every supported database.
+type TagStorage interface {
+	Create(request models.CreateTagRequest) (response models.TagResponse, err error)
+	ReadWithAliases(id models.ID) (response models.TagWithAliasesResponse, err error)
+	ReadMany(IDs []models.ID) (response []models.TagResponse, err error)
+	Update(id models.ID, updateRequest models.UpdateTagRequest) (response models.TagResponse, err error)
+	Delete(id models.ID) (err error)
+	Merge(targetID models.ID, request models.MergeTagsRequest) (response models.TagWithAliasesResponse, err error)
+	CreateAlias(tagID models.ID, request models.CreateTagAliasRequest) (response models.TagWithAliasesResponse, err error)
+	DeleteAlias(tagID models.ID, alias string) (err error)
+	ListPage(request models.ListTagsRequest) (response models.ListResponse[models.TagResponse], err error)
+	Tree() (response []models.TagTreeNode, err error)
+}
+
+// Storage of documents used by DocumentController. Implemented by repository.DocumentRepository for every supported database.
+type DocumentStorage interface {
+	Create(request models.CreateDocumentRequest) (response models.DocumentResponse, err error)
+	Read(id models.ID) (response models.DocumentResponse, err error)
+	ReadMany(IDs []models.ID) (response []models.DocumentResponse, err error)
+	Update(id models.ID, updateRequest models.UpdateDocumentRequest) (response models.DocumentResponse, err error)
+	Delete(id models.ID) (err error)
+	ListPage(request models.ListDocumentsRequest) (response models.ListResponse[models.DocumentResponse], err error)
+	ListRevisions(documentID models.ID) (response []models.DocumentRevision, err error)
+	ReadRevision(documentID models.ID, revision int64) (response models.DocumentRevision, err error)
+	Restore(documentID models.ID, revision int64, author null.String) (response models.DocumentResponse, err error)
+	Bulk(operations []models.BulkOperation) (results []models.BulkOperationResult, err error)
+}
+
+// Storage of deleted documents and tags used by TrashController. Implemented by repository.TrashRepository.
+type TrashStorage interface {
+	List() (response models.TrashResponse, err error)
+	RestoreDocument(id models.ID) (err error)
+	RestoreTag(id models.ID) (err error)
+}
+
+// Index outbox used by AdminController to report and revive entries which failed too many times. Implemented by repository.OutboxRepository.
+type OutboxStorage interface {
+	ListDead() (response []models.OutboxEntry, err error)
+	Revive() (err error)
+}
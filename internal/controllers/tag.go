@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,26 +12,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type DocumentLister interface {
-	ListForTag(tagID models.ID) (response []models.DocumentResponse, err error)
-	ReadMany(IDs []models.ID) (response []models.DocumentResponse, err error)
-}
-
-type Indexer interface {
-	Index(documents []models.DocumentResponse) error
+// Notifies index outbox worker that there are new entries to process
+type IndexNotifier interface {
+	Notify()
 }
 
 type TagController struct {
-	repository         *repository.TagRepository
-	documentRepository DocumentLister
-	indexService       Indexer
+	repository    TagStorage
+	indexNotifier IndexNotifier
 }
 
-func NewTagController(tagRepository *repository.TagRepository, documentRepository DocumentLister, indexService Indexer) *TagController {
+func NewTagController(tagRepository TagStorage, indexNotifier IndexNotifier) *TagController {
 	return &TagController{
-		repository:         tagRepository,
-		documentRepository: documentRepository,
-		indexService:       indexService,
+		repository:    tagRepository,
+		indexNotifier: indexNotifier,
 	}
 }
 
@@ -44,12 +39,26 @@ func (controller *TagController) Create(c *gin.Context) {
 	}
 
 	createdTag, err := controller.repository.Create(createTagRequest)
-	if err != nil {
+	if errors.Is(err, repository.ErrTagParentNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrTagNameTaken) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	// Name of existing alias was passed so canonical tag is returned instead of creating new one
+	if createdTag.Name != createTagRequest.Name {
+		c.JSON(http.StatusOK, createdTag)
+		return
+	}
+
 	c.JSON(http.StatusCreated, createdTag)
 }
 
@@ -61,7 +70,7 @@ func (controller *TagController) Read(c *gin.Context) {
 		return
 	}
 
-	tagResponse, err := controller.repository.Read(int64(id))
+	tagResponse, err := controller.repository.ReadWithAliases(int64(id))
 	if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
@@ -86,31 +95,46 @@ func (controller *TagController) Update(c *gin.Context) {
 		return
 	}
 
-	// TODO: tag update, document listing and reindexing in one transaction
 	tagResponse, err := controller.repository.Update(int64(id), updateTagRequest)
-	if err != nil {
+	if errors.Is(err, repository.ErrTagParentNotFound) || errors.Is(err, repository.ErrTagCycle) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrTagNameTaken) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	tagDocuments, err := controller.documentRepository.ListForTag(int64(id))
+	controller.indexNotifier.Notify()
+
+	c.JSON(http.StatusOK, tagResponse)
+}
+
+func (controller *TagController) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		log.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := controller.indexService.Index(tagDocuments); err != nil {
+	if err := controller.repository.Delete(int64(id)); err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, tagResponse)
+	controller.indexNotifier.Notify()
+
+	c.Status(http.StatusNoContent)
 }
 
-func (controller *TagController) Delete(c *gin.Context) {
+func (controller *TagController) Merge(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		log.Println(err)
@@ -118,33 +142,80 @@ func (controller *TagController) Delete(c *gin.Context) {
 		return
 	}
 
-	// TODO: listing, deleting, reindexing in one transaction
-	tagDocuments, err := controller.documentRepository.ListForTag(int64(id))
-	if err != nil {
+	var mergeTagsRequest models.MergeTagsRequest
+	if err := c.Bind(&mergeTagsRequest); err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagResponse, err := controller.repository.Merge(int64(id), mergeTagsRequest)
+	if errors.Is(err, repository.ErrTagMergeIntoItself) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrTagNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	documentsIDs := make([]models.ID, 0, len(tagDocuments))
-	for _, document := range tagDocuments {
-		documentsIDs = append(documentsIDs, document.ID)
+	controller.indexNotifier.Notify()
+
+	c.JSON(http.StatusOK, tagResponse)
+}
+
+func (controller *TagController) CreateAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := controller.repository.Delete(int64(id)); err != nil {
+	var createTagAliasRequest models.CreateTagAliasRequest
+	if err := c.Bind(&createTagAliasRequest); err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagResponse, err := controller.repository.CreateAlias(int64(id), createTagAliasRequest)
+	if errors.Is(err, repository.ErrTagNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrTagNameTaken) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	documentsWithoutDeletedTag, err := controller.documentRepository.ReadMany(documentsIDs)
+	c.JSON(http.StatusCreated, tagResponse)
+}
+
+func (controller *TagController) DeleteAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		log.Println(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := controller.indexService.Index(documentsWithoutDeletedTag); err != nil {
+	err = controller.repository.DeleteAlias(int64(id), c.Param("alias"))
+	if errors.Is(err, repository.ErrTagAliasNotFound) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
@@ -153,6 +224,17 @@ func (controller *TagController) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (controller *TagController) Tree(c *gin.Context) {
+	response, err := controller.repository.Tree()
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (controller *TagController) List(c *gin.Context) {
 	queryparamIDs, ok := c.GetQueryArray("ids")
 	if ok {
@@ -177,8 +259,20 @@ func (controller *TagController) List(c *gin.Context) {
 		return
 	}
 
-	response, err := controller.repository.List()
-	if err != nil {
+	var listTagsRequest models.ListTagsRequest
+	if err := c.ShouldBindQuery(&listTagsRequest); err != nil {
+		err = fmt.Errorf("unable to bind query params during tags list: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := controller.repository.ListPage(listTagsRequest)
+	if errors.Is(err, repository.ErrInvalidListRequest) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type TrashController struct {
+	repository    TrashStorage
+	indexNotifier IndexNotifier
+}
+
+func NewTrashController(trashRepository TrashStorage, indexNotifier IndexNotifier) *TrashController {
+	return &TrashController{
+		repository:    trashRepository,
+		indexNotifier: indexNotifier,
+	}
+}
+
+func (controller *TrashController) List(c *gin.Context) {
+	response, err := controller.repository.List()
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *TrashController) RestoreDocument(c *gin.Context) {
+	controller.restore(c, controller.repository.RestoreDocument)
+}
+
+func (controller *TrashController) RestoreTag(c *gin.Context) {
+	controller.restore(c, controller.repository.RestoreTag)
+}
+
+func (controller *TrashController) restore(c *gin.Context, restore func(id int64) error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		err = fmt.Errorf("unable to convert id '%s' into int in restore from trash", c.Param("id"))
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := restore(int64(id)); errors.Is(err, repository.ErrNotInTrash) {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to restore from trash: %w", err)
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	controller.indexNotifier.Notify()
+
+	c.Status(http.StatusNoContent)
+}
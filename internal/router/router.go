@@ -3,15 +3,22 @@ package router
 import (
 	"github.com/Wayodeni/tagsearch-backend/internal/controllers"
 	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
-	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(tagRepository *repository.TagRepository, documentRepository *repository.DocumentRepository, indexService *service.IndexService) *gin.Engine {
-	tagController := controllers.NewTagController(tagRepository, documentRepository, indexService)
-	documentController := controllers.NewDocumentController(documentRepository, indexService)
+// Document storage is used both by documents endpoints and by index verification and rebuild
+type DocumentStorage interface {
+	controllers.DocumentStorage
+	service.DocumentIDLister
+}
+
+func NewRouter(tagRepository controllers.TagStorage, documentRepository DocumentStorage, trashRepository controllers.TrashStorage, outboxRepository controllers.OutboxStorage, indexService *service.IndexService, indexNotifier controllers.IndexNotifier, indexPath string) *gin.Engine {
+	tagController := controllers.NewTagController(tagRepository, indexNotifier)
+	documentController := controllers.NewDocumentController(documentRepository, indexNotifier)
 	searchController := controllers.NewSearchController(indexService)
+	adminController := controllers.NewAdminController(indexService, documentRepository, outboxRepository, indexNotifier, indexPath)
+	trashController := controllers.NewTrashController(trashRepository, indexNotifier)
 
 	r := gin.Default()
 	r.Use(cors.Default())
@@ -22,23 +29,49 @@ func NewRouter(tagRepository *repository.TagRepository, documentRepository *repo
 			tags := v1.Group("/tags")
 			{
 				tags.POST("", tagController.Create)
+				tags.GET("/tree", tagController.Tree)
 				tags.GET("/:id", tagController.Read)
 				tags.PATCH("/:id", tagController.Update)
 				tags.DELETE("/:id", tagController.Delete)
+				tags.POST("/:id/merge", tagController.Merge)
+				tags.POST("/:id/aliases", tagController.CreateAlias)
+				tags.DELETE("/:id/aliases/:alias", tagController.DeleteAlias)
 				tags.GET("", tagController.List)
 			}
 			documents := v1.Group("/documents")
 			{
 				documents.POST("", documentController.Create)
+				documents.POST("/bulk", documentController.Bulk)
 				documents.GET("/:id", documentController.Read)
 				documents.PATCH("/:id", documentController.Update)
 				documents.DELETE("/:id", documentController.Delete)
+				documents.GET("/:id/revisions", documentController.ListRevisions)
+				documents.GET("/:id/revisions/diff", documentController.DiffRevisions)
+				documents.POST("/:id/revisions/:rev/restore", documentController.RestoreRevision)
 				documents.GET("", documentController.List)
 			}
+			trash := v1.Group("/trash")
+			{
+				trash.GET("", trashController.List)
+				trash.POST("/documents/:id/restore", trashController.RestoreDocument)
+				trash.POST("/tags/:id/restore", trashController.RestoreTag)
+			}
 			search := v1.Group("/search")
 			{
 				search.GET("", searchController.Search)
 			}
+			suggest := v1.Group("/suggest")
+			{
+				suggest.GET("", searchController.Suggest)
+			}
+			admin := v1.Group("/admin")
+			{
+				admin.GET("/verify", adminController.Verify)
+				admin.POST("/repair", adminController.Repair)
+				admin.POST("/reindex", adminController.Reindex)
+				admin.GET("/reindex", adminController.ReindexStatus)
+				admin.GET("/relevance/evaluate", adminController.EvaluateRelevance)
+			}
 		}
 	}
 
@@ -2,17 +2,24 @@ package router
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/Wayodeni/tagsearch-backend/internal/controllers"
+	service "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/service/outbox"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
 	utilities "github.com/Wayodeni/tagsearch-backend/internal/tests/index"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
 )
 
 func newTestRouter() (*gin.Engine, func()) {
@@ -22,7 +29,8 @@ func newTestRouter() (*gin.Engine, func()) {
 
 	testIndexService, _, indexCleanupFunc := utilities.NewTestIndexService("../tests/index/lenta-ru-news.csv")
 
-	return NewRouter(tagRepository, documentRepository, testIndexService),
+	outboxRepository := repository.NewOutboxRepository(db)
+	return NewRouter(tagRepository, documentRepository, repository.NewTrashRepository(db), outboxRepository, testIndexService, outbox.NewWorker(outboxRepository, documentRepository, testIndexService), ""),
 		func() {
 			db.Close()
 			indexCleanupFunc()
@@ -112,3 +120,111 @@ func Test_Find_None(t *testing.T) {
 		require.Equal(t, len(actual), 0, "test case '%s' failed", queryParams)
 	}
 }
+
+// Router whose search is served by in-memory index of passed documents
+func newTestMemRouter(documents []models.DocumentResponse) (*gin.Engine, func()) {
+	db := db.NewDb(":memory:")
+	tagRepository := repository.NewTagRepository(db)
+	documentRepository := repository.NewDocumentRepository(db, tagRepository)
+
+	testIndexService, indexCleanupFunc := utilities.NewTestMemIndexService(documents)
+
+	outboxRepository := repository.NewOutboxRepository(db)
+	return NewRouter(tagRepository, documentRepository, repository.NewTrashRepository(db), outboxRepository, testIndexService, outbox.NewWorker(outboxRepository, documentRepository, testIndexService), ""),
+		func() {
+			db.Close()
+			indexCleanupFunc()
+		}
+}
+
+func Test_Find_IncludeDescendants(t *testing.T) {
+	sport := models.TagResponse{ID: 1, Name: "sport", Assigned: true}
+	football := models.TagResponse{ID: 2, Name: "football", Assigned: true, ParentID: null.IntFrom(1)}
+	router, cleanupFunc := newTestMemRouter([]models.DocumentResponse{
+		{ID: 1, Name: "Olympics", Body: "sport", Tags: []models.TagResponse{sport}},
+		{ID: 2, Name: "World cup", Body: "football", Tags: []models.TagResponse{football}},
+	})
+	defer cleanupFunc()
+
+	testCases := []struct {
+		includeDescendants string
+		expected           []models.ID
+	}{
+		{includeDescendants: "", expected: []models.ID{1}},
+		{includeDescendants: "false", expected: []models.ID{1}},
+		{includeDescendants: "true", expected: []models.ID{1, 2}},
+	}
+
+	for _, testCase := range testCases {
+		params := url.Values{"tags[]": {"sport"}, "sort": {"id"}}
+		if testCase.includeDescendants != "" {
+			params.Set("includeDescendants", testCase.includeDescendants)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", apiURL+params.Encode(), nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response service.SearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+		actual := make([]models.ID, 0, len(response.Documents))
+		for _, document := range response.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, "includeDescendants '%s'", testCase.includeDescendants)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", apiURL+"tags[]=sport&includeDescendants=maybe", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// Endless body of whitespace
+type spaceReader struct{}
+
+func (spaceReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
+
+func Test_Bulk_Limits(t *testing.T) {
+	router, cleanupFunc := newTestMemRouter(nil)
+	defer cleanupFunc()
+
+	operation := `{"op": "delete", "id": 1}`
+	tooManyOperations := strings.Repeat(operation+",", controllers.MaxBulkOperations+1)
+	testCases := []struct {
+		contentType string
+		body        io.Reader
+		status      int
+		error       string
+	}{
+		// Operations after limit are not valid JSON, request must be rejected before they are read
+		{contentType: "application/json", body: strings.NewReader("[" + tooManyOperations + "not json"), status: http.StatusBadRequest, error: "must not contain more than"},
+		{contentType: "application/x-ndjson", body: strings.NewReader(strings.ReplaceAll(tooManyOperations, ",", "\n") + "not json"), status: http.StatusBadRequest, error: "must not contain more than"},
+		{contentType: "application/json", body: io.MultiReader(strings.NewReader("["), spaceReader{}), status: http.StatusRequestEntityTooLarge, error: "must not be larger than"},
+		{contentType: "application/json", body: strings.NewReader("[]"), status: http.StatusBadRequest, error: "at least one operation"},
+		{contentType: "application/json", body: strings.NewReader(`{"op": "delete", "id": 1}`), status: http.StatusBadRequest, error: "JSON array"},
+		{contentType: "application/json", body: strings.NewReader("[" + operation), status: http.StatusBadRequest, error: "unable to read bulk request"},
+	}
+
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/documents/bulk", testCase.body)
+		req.Header.Set("Content-Type", testCase.contentType)
+		router.ServeHTTP(w, req)
+		require.Equal(t, testCase.status, w.Code, testCase.error)
+		require.Contains(t, w.Body.String(), testCase.error)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/documents/bulk", strings.NewReader("["+operation+"]"))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+/*
+Position in search results after which next page starts. It is passed to client as opaque token
+and contains sort values of the last document of page in terms of bleve SearchAfter.
+Cursor is valid only for sort order it was built with.
+*/
+type searchCursor struct {
+	Order []string `json:"o"`
+	// Sort values are arbitrary bytes (numeric fields are prefix coded) so they are not stored as strings
+	After [][]byte `json:"a"`
+	// Time of first page search in unix nanoseconds. Recency decay of scores is counted up to it on every page,
+	// otherwise scores would change between pages and documents would be skipped or repeated
+	Now int64 `json:"n"`
+}
+
+// Returns token which continues search with passed sort order after passed hit
+func encodeCursor(order []string, sortOrder search.SortOrder, hit *search.DocumentMatch, now time.Time) (string, error) {
+	cursor := searchCursor{
+		Order: order,
+		After: make([][]byte, 0, len(hit.Sort)),
+		Now:   now.UnixNano(),
+	}
+	for i, value := range hit.Sort {
+		// Score is not a part of sort values, bleve expects it formatted as float
+		if i < len(sortOrder) && sortOrder[i].RequiresScoring() {
+			value = strconv.FormatFloat(hit.Score, 'g', -1, 64)
+		}
+		cursor.After = append(cursor.After, []byte(value))
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+/*
+Returns SearchAfter values of token and time of first page search.
+Returned error wraps ErrInvalidCursor if token is malformed or built for other sort order.
+*/
+func decodeCursor(token string, order []string) ([]string, time.Time, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if !slices.Equal(cursor.Order, order) {
+		return nil, time.Time{}, fmt.Errorf("%w: cursor was built for other sort order", ErrInvalidCursor)
+	}
+	if len(cursor.After) != len(order) {
+		return nil, time.Time{}, fmt.Errorf("%w: cursor has %d sort values, expected %d", ErrInvalidCursor, len(cursor.After), len(order))
+	}
+	if cursor.Now == 0 {
+		return nil, time.Time{}, fmt.Errorf("%w: cursor has no search time", ErrInvalidCursor)
+	}
+
+	after := make([]string, 0, len(cursor.After))
+	for _, value := range cursor.After {
+		after = append(after, string(value))
+	}
+	return after, time.Unix(0, cursor.Now), nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	DateHistogramIntervalDay   = "day"
+	DateHistogramIntervalMonth = "month"
+
+	DateHistogramFieldCreated = "created"
+	DateHistogramFieldUpdated = "updated"
+
+	// Histogram with more buckets is rejected, request should be narrowed with date range or use bigger interval
+	MaxDateHistogramBuckets = 1000
+
+	// Name of facet with date histogram in search request
+	dateHistogramFacet = "dateHistogram"
+)
+
+var (
+	ErrInvalidDateRange     = errors.New("invalid date range")
+	ErrInvalidDateHistogram = errors.New("invalid date histogram request")
+)
+
+// Date histogram fields accepted in search request mapped to index fields
+var dateHistogramFields = map[string]string{
+	DateHistogramFieldCreated: createdAtField,
+	DateHistogramFieldUpdated: updatedAtField,
+}
+
+type DateHistogramRequest struct {
+	Field    string `form:"histogramField" json:"field"`
+	Interval string `form:"histogram" json:"interval"`
+}
+
+// Count of found documents created or updated within [Start, End)
+type DateBucket struct {
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	DocumentCount DocumentCount `json:"documentCount"`
+}
+
+/*
+Fills zero values with defaults and checks that field and interval are known.
+Returned error wraps ErrInvalidDateHistogram so it can be reported as bad request.
+*/
+func (request *DateHistogramRequest) validate() error {
+	if request.Field == "" {
+		request.Field = DateHistogramFieldCreated
+	}
+	if request.Interval == "" {
+		request.Interval = DateHistogramIntervalDay
+	}
+
+	if _, ok := dateHistogramFields[request.Field]; !ok {
+		return fmt.Errorf("%w: unknown field '%s'", ErrInvalidDateHistogram, request.Field)
+	}
+	if request.Interval != DateHistogramIntervalDay && request.Interval != DateHistogramIntervalMonth {
+		return fmt.Errorf("%w: unknown interval '%s'", ErrInvalidDateHistogram, request.Interval)
+	}
+
+	return nil
+}
+
+// Returns start of interval which contains passed time, in UTC
+func (request *DateHistogramRequest) truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if request.Interval == DateHistogramIntervalMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (request *DateHistogramRequest) next(start time.Time) time.Time {
+	if request.Interval == DateHistogramIntervalMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Returns consecutive buckets covering period from first to last, both inclusive
+func (request *DateHistogramRequest) buckets(first, last time.Time) ([]DateBucket, error) {
+	var buckets []DateBucket
+	for start := request.truncate(first); !start.After(last); start = request.next(start) {
+		if len(buckets) == MaxDateHistogramBuckets {
+			return nil, fmt.Errorf("%w: more than %d buckets, narrow date range or use bigger interval", ErrInvalidDateHistogram, MaxDateHistogramBuckets)
+		}
+		buckets = append(buckets, DateBucket{Start: start, End: request.next(start)})
+	}
+	return buckets, nil
+}
+
+// Returns error wrapping ErrInvalidDateRange if any range of request has start after end
+func (searchQuery *SearchDocumentRequest) validateDateRanges() error {
+	if !searchQuery.CreatedFrom.IsZero() && !searchQuery.CreatedTo.IsZero() && searchQuery.CreatedFrom.After(searchQuery.CreatedTo) {
+		return fmt.Errorf("%w: createdFrom is after createdTo", ErrInvalidDateRange)
+	}
+	if !searchQuery.UpdatedFrom.IsZero() && !searchQuery.UpdatedTo.IsZero() && searchQuery.UpdatedFrom.After(searchQuery.UpdatedTo) {
+		return fmt.Errorf("%w: updatedFrom is after updatedTo", ErrInvalidDateRange)
+	}
+	return nil
+}
+
+// Returns queries for date ranges of request. Both bounds are inclusive, zero bound is not checked.
+func (searchQuery *SearchDocumentRequest) dateRangeQueries() (queries []query.Query) {
+	inclusive := true
+	addRange := func(field string, from, to time.Time) {
+		if from.IsZero() && to.IsZero() {
+			return
+		}
+		dateRangeQuery := bleve.NewDateRangeInclusiveQuery(from, to, &inclusive, &inclusive)
+		dateRangeQuery.SetField(field)
+		queries = append(queries, dateRangeQuery)
+	}
+
+	addRange(createdAtField, searchQuery.CreatedFrom, searchQuery.CreatedTo)
+	addRange(updatedAtField, searchQuery.UpdatedFrom, searchQuery.UpdatedTo)
+	return queries
+}
+
+/*
+Returns earliest and latest value of date field among documents matching query.
+Found is false if there are no such documents.
+*/
+func (service *IndexService) dateBounds(searchQuery query.Query, field string) (first, last time.Time, found bool, err error) {
+	bound := func(sortOrder string) (time.Time, bool, error) {
+		searchRequest := bleve.NewSearchRequestOptions(searchQuery, 1, 0, false)
+		searchRequest.SortBy([]string{sortOrder})
+		searchRequest.Fields = []string{field}
+
+		results, err := service.index.Search(searchRequest)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if len(results.Hits) == 0 {
+			return time.Time{}, false, nil
+		}
+
+		value, _ := results.Hits[0].Fields[field].(string)
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unable to parse stored field '%s' of document '%s': %w", field, results.Hits[0].ID, err)
+		}
+		return t, true, nil
+	}
+
+	if first, found, err = bound(field); err != nil || !found {
+		return first, last, found, err
+	}
+	last, found, err = bound("-" + field)
+	return first, last, found, err
+}
+
+/*
+Adds date range facet with bucket per interval between earliest and latest matched document to search request.
+Returned buckets are filled with counts by fillDateHistogram after search.
+*/
+func (service *IndexService) addDateHistogram(searchRequest *bleve.SearchRequest, request *DateHistogramRequest) ([]DateBucket, error) {
+	field := dateHistogramFields[request.Field]
+	first, last, found, err := service.dateBounds(searchRequest.Query, field)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	buckets, err := request.buckets(first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	facetRequest := bleve.NewFacetRequest(field, len(buckets))
+	for _, bucket := range buckets {
+		facetRequest.AddDateTimeRange(bucket.Start.Format(time.RFC3339), bucket.Start, bucket.End)
+	}
+	searchRequest.AddFacet(dateHistogramFacet, facetRequest)
+
+	return buckets, nil
+}
+
+// Sets document counts of buckets from date range facet of search results
+func fillDateHistogram(buckets []DateBucket, results *bleve.SearchResult) []DateBucket {
+	facet, ok := results.Facets[dateHistogramFacet]
+	if !ok {
+		return buckets
+	}
+
+	counts := make(map[string]DocumentCount, len(facet.DateRanges))
+	for _, dateRange := range facet.DateRanges {
+		counts[dateRange.Name] = dateRange.Count
+	}
+	for i := range buckets {
+		buckets[i].DocumentCount = counts[buckets[i].Start.Format(time.RFC3339)]
+	}
+	return buckets
+}
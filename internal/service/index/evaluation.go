@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+)
+
+// Number of top documents evaluated if it is not requested
+const DefaultEvaluationSize = 10
+
+var (
+	ErrInvalidJudgedQueries     = errors.New("invalid judged queries")
+	ErrInvalidEvaluationRequest = errors.New("invalid evaluation request")
+)
+
+// Search query with grades of documents it should find. Documents which are not graded are not relevant
+type JudgedQuery struct {
+	Query string   `json:"query"`
+	Tags  []string `json:"tags"`
+	// Relevance grades by document IDs, documents with positive grade are relevant
+	Judgments map[models.ID]int `json:"judgments"`
+}
+
+type EvaluationRequest struct {
+	Profile string `form:"profile" json:"profile"` // default profile is evaluated if empty
+	K       int    `form:"k" json:"k"`             // number of top documents evaluated, DefaultEvaluationSize if zero
+}
+
+type QueryEvaluation struct {
+	Query string   `json:"query"`
+	Tags  []string `json:"tags,omitempty"`
+	// Normalized discounted cumulative gain of top K documents
+	NDCG float64 `json:"ndcg"`
+	// Part of top K documents which are relevant
+	Precision float64 `json:"precision"`
+	// IDs of top K found documents in order of ranking
+	DocumentIDs []models.ID `json:"documentIds"`
+}
+
+type EvaluationResponse struct {
+	Profile string `json:"profile"`
+	K       int    `json:"k"`
+	// Metrics averaged over all judged queries
+	NDCG      float64           `json:"ndcg"`
+	Precision float64           `json:"precision"`
+	Queries   []QueryEvaluation `json:"queries"`
+}
+
+// Reads JSON array of judged queries from file
+func LoadJudgedQueries(path string) ([]JudgedQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var judgedQueries []JudgedQuery
+	if err := json.Unmarshal(data, &judgedQueries); err != nil {
+		return nil, fmt.Errorf("unable to parse judged queries: %w", err)
+	}
+
+	for i, judgedQuery := range judgedQueries {
+		if err := judgedQuery.validate(); err != nil {
+			return nil, fmt.Errorf("judged query %d: %w", i, err)
+		}
+	}
+
+	return judgedQueries, nil
+}
+
+func (judgedQuery *JudgedQuery) validate() error {
+	if judgedQuery.Query == "" && len(judgedQuery.Tags) == 0 {
+		return fmt.Errorf("%w: query or tags must be set", ErrInvalidJudgedQueries)
+	}
+
+	hasRelevant := false
+	for ID, grade := range judgedQuery.Judgments {
+		if grade < 0 {
+			return fmt.Errorf("%w: grade of document %d must not be negative", ErrInvalidJudgedQueries, ID)
+		}
+		hasRelevant = hasRelevant || grade > 0
+	}
+	// Metrics of query without relevant documents are zero for any ranking
+	if !hasRelevant {
+		return fmt.Errorf("%w: query '%s' has no relevant documents", ErrInvalidJudgedQueries, judgedQuery.Query)
+	}
+
+	return nil
+}
+
+/*
+Searches every judged query with relevance profile and compares top K found documents with judgments.
+Ranking is evaluated with NDCG@K, where gain of document is 2^grade - 1, and with precision@K.
+*/
+func (service *IndexService) EvaluateRelevance(request *EvaluationRequest) (response EvaluationResponse, err error) {
+	if request.K == 0 {
+		request.K = DefaultEvaluationSize
+	}
+	if request.K < 0 || request.K > service.MaxPageSize {
+		return response, fmt.Errorf("%w: k must be between 1 and %d", ErrInvalidEvaluationRequest, service.MaxPageSize)
+	}
+	if len(service.JudgedQueries) == 0 {
+		return response, fmt.Errorf("%w: no judged queries are configured", ErrInvalidEvaluationRequest)
+	}
+
+	// Unknown profile is reported before searching
+	if _, err := service.relevanceProfile(request.Profile); err != nil {
+		return response, err
+	}
+
+	response.Profile = request.Profile
+	response.K = request.K
+	response.Queries = make([]QueryEvaluation, 0, len(service.JudgedQueries))
+	for _, judgedQuery := range service.JudgedQueries {
+		searchResponse, err := service.Find(&SearchDocumentRequest{
+			Query:    judgedQuery.Query,
+			Tags:     judgedQuery.Tags,
+			PageSize: request.K,
+			Profile:  request.Profile,
+		})
+		if err != nil {
+			return response, fmt.Errorf("unable to search judged query '%s': %w", judgedQuery.Query, err)
+		}
+
+		IDs := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			IDs = append(IDs, document.ID)
+		}
+
+		evaluation := QueryEvaluation{
+			Query:       judgedQuery.Query,
+			Tags:        judgedQuery.Tags,
+			NDCG:        ndcg(IDs, judgedQuery.Judgments, request.K),
+			Precision:   precision(IDs, judgedQuery.Judgments, request.K),
+			DocumentIDs: IDs,
+		}
+		response.NDCG += evaluation.NDCG / float64(len(service.JudgedQueries))
+		response.Precision += evaluation.Precision / float64(len(service.JudgedQueries))
+		response.Queries = append(response.Queries, evaluation)
+	}
+
+	return response, nil
+}
+
+// Returns DCG of top k ranked documents divided by DCG of ideal ranking of judged documents
+func ndcg(IDs []models.ID, judgments map[models.ID]int, k int) float64 {
+	grades := make([]int, 0, len(IDs))
+	for _, ID := range IDs {
+		grades = append(grades, judgments[ID])
+	}
+
+	idealGrades := make([]int, 0, len(judgments))
+	for _, grade := range judgments {
+		idealGrades = append(idealGrades, grade)
+	}
+	slices.SortFunc(idealGrades, func(a, b int) int { return b - a })
+
+	idealDCG := dcg(idealGrades, k)
+	if idealDCG == 0 {
+		return 0
+	}
+	return dcg(grades, k) / idealDCG
+}
+
+func dcg(grades []int, k int) (result float64) {
+	for i, grade := range grades[:min(k, len(grades))] {
+		result += (math.Exp2(float64(grade)) - 1) / math.Log2(float64(i+2))
+	}
+	return result
+}
+
+// Returns part of k top positions taken by relevant documents
+func precision(IDs []models.ID, judgments map[models.ID]int, k int) float64 {
+	relevant := 0
+	for _, ID := range IDs[:min(k, len(IDs))] {
+		if judgments[ID] > 0 {
+			relevant++
+		}
+	}
+	return float64(relevant) / float64(k)
+}
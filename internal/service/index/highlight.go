@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/ansi"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/plain"
+	simpleFragmenter "github.com/blevesearch/bleve/v2/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/v2/search/highlight/highlighter/simple"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+const (
+	HighlightStyleHTML  = "html"
+	HighlightStyleANSI  = "ansi"
+	HighlightStylePlain = "plain"
+
+	DefaultHighlightFragmentSize = 200
+	MaxHighlightFragmentSize     = 1000
+	DefaultHighlightFragments    = 1
+	MaxHighlightFragments        = 10
+)
+
+var (
+	ErrInvalidHighlightRequest = errors.New("invalid highlight request")
+)
+
+// Fields which are highlighted when highlighting is requested
+var highlightFields = []string{nameField, bodyField}
+
+type HighlightRequest struct {
+	Style        string `form:"highlightStyle" json:"style"`
+	FragmentSize int    `form:"fragmentSize" json:"fragmentSize"`
+	Fragments    int    `form:"fragments" json:"fragments"`
+}
+
+// Highlight contains best fragments of document found by query, grouped by field name
+type Highlight map[string][]string
+
+/*
+Fills zero values with defaults and checks that options are within bounds.
+Returned error wraps ErrInvalidHighlightRequest so it can be reported as bad request.
+*/
+func (request *HighlightRequest) validate() error {
+	if request.Style == "" {
+		request.Style = HighlightStyleHTML
+	}
+	if request.FragmentSize == 0 {
+		request.FragmentSize = DefaultHighlightFragmentSize
+	}
+	if request.Fragments == 0 {
+		request.Fragments = DefaultHighlightFragments
+	}
+
+	if request.FragmentSize < 0 || request.FragmentSize > MaxHighlightFragmentSize {
+		return fmt.Errorf("%w: fragment size must be between 1 and %d", ErrInvalidHighlightRequest, MaxHighlightFragmentSize)
+	}
+	if request.Fragments < 0 || request.Fragments > MaxHighlightFragments {
+		return fmt.Errorf("%w: fragments quantity must be between 1 and %d", ErrInvalidHighlightRequest, MaxHighlightFragments)
+	}
+
+	return nil
+}
+
+/*
+Builds highlighter for request. Highlighters registered in bleve config always
+return single fragment of predefined size, so highlighter is constructed per request
+to respect requested fragment size and fragments quantity.
+*/
+func (request *HighlightRequest) highlighter() (highlight.Highlighter, error) {
+	var formatter highlight.FragmentFormatter
+	switch request.Style {
+	case HighlightStyleHTML:
+		formatter = html.NewFragmentFormatter("<mark>", "</mark>")
+	case HighlightStyleANSI:
+		formatter = ansi.NewFragmentFormatter(ansi.DefaultAnsiHighlight)
+	case HighlightStylePlain:
+		formatter = plain.NewFragmentFormatter("<start>", "<end>")
+	default:
+		return nil, fmt.Errorf("%w: unknown highlight style '%s'", ErrInvalidHighlightRequest, request.Style)
+	}
+
+	return simpleHighlighter.NewHighlighter(
+		simpleFragmenter.NewFragmenter(request.FragmentSize),
+		formatter,
+		simpleHighlighter.DefaultSeparator,
+	), nil
+}
+
+// Returns best fragments for every highlighted field of matched document
+func highlightMatch(highlighter highlight.Highlighter, match *search.DocumentMatch, document index.Document, fragments int) Highlight {
+	result := Highlight{}
+	for _, field := range highlightFields {
+		if _, ok := match.Locations[field]; !ok {
+			continue
+		}
+		fieldFragments := highlighter.BestFragmentsInField(match, document, field, fragments)
+		if len(fieldFragments) > 0 {
+			result[field] = fieldFragments
+		}
+	}
+	return result
+}
@@ -2,19 +2,51 @@ package service
 
 import (
 	"fmt"
-	"math"
+	"os"
 	"slices"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
 )
 
 type SearchDocumentRequest struct {
-	Query      string   `form:"query" json:"query"`
-	Tags       []string `form:"tags" json:"tags"`
-	PageSize   int      `form:"pageSize" json:"pageSize"`
-	PageNumber int      `form:"pageNumber" json:"pageNumber"`
+	Query     string   `form:"query" json:"query"`
+	Tags      []string `form:"tags" json:"tags"`
+	TagFilter string   `form:"tagFilter" json:"tagFilter"` // boolean expression over tag names, see tagFilter
+	// If true, tag in Tags or TagFilter also matches documents which have any of its descendant tags
+	IncludeDescendants bool     `form:"includeDescendants" json:"includeDescendants"`
+	PageSize           int      `form:"pageSize" json:"pageSize"`
+	PageNumber         int      `form:"pageNumber" json:"pageNumber"`
+	Sort               []string `form:"sort" json:"sort"` // sort keys, `-` prefix means descending order
+	// Token from NextCursor of previous response. If set, page after cursor is returned and PageNumber is ignored
+	Cursor string `form:"cursor" json:"cursor"`
+
+	// Documents created or updated within range are found. Both bounds are inclusive, zero bound is not checked
+	CreatedFrom time.Time `form:"createdFrom" json:"createdFrom"`
+	CreatedTo   time.Time `form:"createdTo" json:"createdTo"`
+	UpdatedFrom time.Time `form:"updatedFrom" json:"updatedFrom"`
+	UpdatedTo   time.Time `form:"updatedTo" json:"updatedTo"`
+
+	Highlight     *HighlightRequest     `json:"highlight"`     // highlighting is disabled if nil
+	DateHistogram *DateHistogramRequest `json:"dateHistogram"` // histogram is not built if nil
+
+	// If query finds nothing, documents found by query with corrected spelling are returned
+	AutoCorrect bool `form:"autoCorrect" json:"autoCorrect"`
+
+	// Query options. If any of them is set, Query is searched as plain text instead of query string syntax
+	Fuzziness int      `form:"fuzziness" json:"fuzziness"` // maximum edits of every query term
+	Phrase    bool     `form:"phrase" json:"phrase"`       // query is searched as phrase
+	Slop      int      `form:"slop" json:"slop"`           // maximum distance of phrase terms from their places in phrase
+	Operator  string   `form:"operator" json:"operator"`   // `or` (default) or `and`, whether all terms must be found
+	Fields    []string `form:"fields" json:"fields"`       // `name` and `body`, all of them by default
+
+	// Name of relevance profile adjusting scores of found documents, default profile is used if empty
+	Profile string `form:"profile" json:"profile"`
 }
 
 type TagName = string
@@ -23,20 +55,52 @@ type SearchResponse struct {
 	Documents                []models.DocumentResponse `json:"documents,omitempty"`
 	Tags                     []TagBucket               `json:"tags,omitempty"`
 	DocumentsFound           int64                     `json:"documentsFound"`
-	Pages                    int                       `json:"pages"`
-	RequestPageIsOutOfBounds bool                      `json:"requestPageIsOutOfBounds"` // this flag tells frontend to change current page to Pages field of response
+	Pages                    int                       `json:"pages"`                    // same as TotalPages, kept for existing clients
+	RequestPageIsOutOfBounds bool                      `json:"requestPageIsOutOfBounds"` // this flag tells frontend to change current page to Page field of response
+	// One based number of returned page. Requested page after the last one is clamped to the last page.
+	// Zero if page was requested with cursor because its position is unknown then
+	Page          int                     `json:"page"`
+	PageSize      int                     `json:"pageSize"`
+	TotalPages    int                     `json:"totalPages"`
+	HasNext       bool                    `json:"hasNext"`
+	HasPrev       bool                    `json:"hasPrev"`
+	Highlights    map[models.ID]Highlight `json:"highlights,omitempty"`
+	DateHistogram []DateBucket            `json:"dateHistogram,omitempty"`
+	// Token to request next page with, empty if this page is the last one
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Query with corrected spelling, set if original query finds few documents and suggested one finds more
+	SuggestedQuery string `json:"suggestedQuery,omitempty"`
+	// Documents are found by SuggestedQuery because original query found nothing
+	AutoCorrected bool `json:"autoCorrected,omitempty"`
 }
 
 type TagBucket struct {
 	models.TagResponse
 	DocumentCount DocumentCount `json:"documentCount"`
-	Selected      bool          `json:"selected"`
+	// Count of found documents which have this tag or any of its descendants
+	RollupDocumentCount DocumentCount `json:"rollupDocumentCount"`
+	Selected            bool          `json:"selected"`
+	Excluded            bool          `json:"excluded"` // tag is negated in tag filter of request
 }
 
 type IndexDocument struct {
-	Name string   `json:"name"`
-	Body string   `json:"body"`
-	Tags []string `json:"tags"`
+	ID       models.ID `json:"id"`
+	Name     string    `json:"name"`
+	Body     string    `json:"body"`
+	Language string    `json:"language"`
+	// Name and body analyzed for document language, fields of other languages are empty
+	NameRu    string   `json:"name_ru,omitempty"`
+	BodyRu    string   `json:"body_ru,omitempty"`
+	NameEn    string   `json:"name_en,omitempty"`
+	BodyEn    string   `json:"body_en,omitempty"`
+	Tags      []string `json:"tags"`
+	TagsCount int      `json:"tagsCount"`
+	// Tags of document with their ids for suggestions, see tagSuggestTerms
+	TagsSuggest []string `json:"tagsSuggest"`
+	// Names of document tags and all of their ancestors
+	RollupTags []string  `json:"rollupTags"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
 func (documentResponse *IndexDocument) Type() string {
@@ -50,12 +114,31 @@ type DocumentReadManyer interface {
 type TagNameLister interface {
 	List() (response []models.TagResponse, err error)
 	ReadManyByNames(names []string) (response []models.TagResponse, err error)
+	ResolveAliases(names []string) (response map[string]models.TagResponse, err error)
 }
 
 type IndexService struct {
+	// Guards index, locked for writing only when index is swapped after rebuild
+	mutex              sync.RWMutex
 	index              bleve.Index
 	documentRepository DocumentReadManyer
 	tagRepository      TagNameLister
+
+	rebuildMutex  sync.Mutex
+	rebuildStatus RebuildStatus
+	// Documents changed while rebuild is running, nil if rebuild is not running
+	rebuildDirty map[models.ID]struct{}
+	// Moves index directories during swap, replaced in tests to simulate filesystem failures
+	renameDir func(from string, to string) error
+
+	// Search requests with bigger page size are rejected
+	MaxPageSize int
+	// Spelling correction is suggested if query finds fewer documents
+	SpellingThreshold int
+	// Relevance profiles by their names, selected by Profile of search request
+	RelevanceProfiles map[string]RelevanceProfile
+	// Queries with relevance of found documents judged manually, used to evaluate relevance profiles
+	JudgedQueries []JudgedQuery
 }
 
 func NewIndexService(index bleve.Index, documentRepository DocumentReadManyer, tagRepository TagNameLister) *IndexService {
@@ -63,38 +146,190 @@ func NewIndexService(index bleve.Index, documentRepository DocumentReadManyer, t
 		index:              index,
 		documentRepository: documentRepository,
 		tagRepository:      tagRepository,
+		renameDir:          os.Rename,
+		MaxPageSize:        DefaultMaxPageSize,
+		SpellingThreshold:  DefaultSpellingThreshold,
 	}
 }
 
+/*
+Finds documents by request. If query finds fewer documents than SpellingThreshold,
+query with corrected spelling is suggested when it finds more documents. Documents found by
+suggested query are returned instead if nothing is found and AutoCorrect is requested.
+*/
 func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response SearchResponse, err error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if response, err = service.find(searchQuery); err != nil {
+		return response, err
+	}
+	if searchQuery.Query == "" || response.DocumentsFound >= int64(service.SpellingThreshold) {
+		return response, nil
+	}
+
+	suggestedQuery, err := service.correctSpelling(searchQuery.Query)
+	if err != nil {
+		return response, fmt.Errorf("unable to correct query spelling: %w", err)
+	}
+	if suggestedQuery == "" {
+		return response, nil
+	}
+
+	suggestedSearchQuery := *searchQuery
+	suggestedSearchQuery.Query = suggestedQuery
+	suggestedResponse, err := service.find(&suggestedSearchQuery)
+	if err != nil {
+		return response, err
+	}
+	if suggestedResponse.DocumentsFound <= response.DocumentsFound {
+		return response, nil
+	}
+
+	if searchQuery.AutoCorrect && response.DocumentsFound == 0 {
+		suggestedResponse.SuggestedQuery = suggestedQuery
+		suggestedResponse.AutoCorrected = true
+		return suggestedResponse, nil
+	}
+	response.SuggestedQuery = suggestedQuery
+	return response, nil
+}
+
+func (service *IndexService) find(searchQuery *SearchDocumentRequest) (response SearchResponse, err error) {
+	if searchQuery.Highlight != nil {
+		if err := searchQuery.Highlight.validate(); err != nil {
+			return response, err
+		}
+	}
+
+	if searchQuery.DateHistogram != nil {
+		if err := searchQuery.DateHistogram.validate(); err != nil {
+			return response, err
+		}
+	}
+
+	if err := searchQuery.validateDateRanges(); err != nil {
+		return response, err
+	}
+
+	if err := searchQuery.validatePagination(service.MaxPageSize); err != nil {
+		return response, err
+	}
+
+	if err := searchQuery.validateQueryOptions(); err != nil {
+		return response, err
+	}
+
+	profile, err := service.relevanceProfile(searchQuery.Profile)
+	if err != nil {
+		return response, err
+	}
+
+	sortOrder, sortOrderStrings, err := getSortOrder(searchQuery.Sort)
+	if err != nil {
+		return response, err
+	}
+
+	// Pages after cursor are searched at time of first page, so scores decayed by recency don't change between pages
+	var searchAfter []string
+	now := time.Now()
+	if searchQuery.Cursor != "" {
+		if searchAfter, now, err = decodeCursor(searchQuery.Cursor, sortOrderStrings); err != nil {
+			return response, err
+		}
+	}
+
+	var filter *tagFilter
+	if searchQuery.TagFilter != "" {
+		filter, err = parseTagFilter(searchQuery.TagFilter)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	// Tags may be passed by aliases, index contains only canonical names
+	queryTagsNames, err := service.resolveAliases(searchQuery.Tags, filter)
+	if err != nil {
+		return response, err
+	}
+
 	booleanQuery := bleve.NewBooleanQuery()
-	matchQuery := bleve.NewQueryStringQuery(searchQuery.Query)
 
 	// Adding match query only if it presents
-	if searchQuery.Query != "" && searchQuery.Query[len(searchQuery.Query)-1] != '-' {
+	var matchQuery query.Query
+	if searchQuery.Query != "" && searchQuery.hasQueryOptions() {
+		matchQuery = service.optionsQuery(searchQuery)
+		booleanQuery.AddMust(matchQuery)
+	} else if searchQuery.Query != "" && searchQuery.Query[len(searchQuery.Query)-1] != '-' {
+		// Query string is parsed here to search its terms in fields of every language
+		parsedQuery, err := bleve.NewQueryStringQuery(searchQuery.Query).Parse()
+		if err != nil {
+			return response, err
+		}
+		matchQuery = expandLanguageFields(parsedQuery)
 		booleanQuery.AddMust(matchQuery)
 	}
 
+	// Documents with descendant tags are matched with field containing all ancestors of document tags
+	queryTagsField := tagsField
+	if searchQuery.IncludeDescendants {
+		queryTagsField = rollupTagsField
+	}
+
 	// Adding term query per tag if any tags present
-	queryTags := make([]models.TagResponse, 0, len(searchQuery.Tags))
-	if len(searchQuery.Tags) > 0 {
-		for _, tag := range searchQuery.Tags {
-			termQuery := bleve.NewTermQuery(tag)
-			termQuery.SetField("tags")
-			booleanQuery.AddMust(termQuery)
-		}
-		queryTags, err = service.tagRepository.ReadManyByNames(searchQuery.Tags)
+	for _, tag := range queryTagsNames {
+		termQuery := bleve.NewTermQuery(tag)
+		termQuery.SetField(queryTagsField)
+		booleanQuery.AddMust(termQuery)
+	}
+
+	// Adding date ranges if any present
+	dateRangeQueries := searchQuery.dateRangeQueries()
+	for _, dateRangeQuery := range dateRangeQueries {
+		booleanQuery.AddMust(dateRangeQuery)
+	}
+
+	// Adding tag filter expression if it presents
+	selectedTagsNames := slices.Clone(queryTagsNames)
+	var excludedTagsNames []TagName
+	if filter != nil {
+		booleanQuery.AddMust(filter.query(queryTagsField))
+		includedTagsNames, filterExcludedTagsNames := filter.tags()
+		// Such request can't find anything and its tag would be reported both selected and excluded
+		for _, name := range queryTagsNames {
+			if slices.Contains(filterExcludedTagsNames, name) {
+				return response, fmt.Errorf("%w: tag '%s' is required by tags and negated in filter", ErrInvalidTagFilter, name)
+			}
+		}
+		selectedTagsNames = append(selectedTagsNames, includedTagsNames...)
+		excludedTagsNames = filterExcludedTagsNames
+	}
+
+	queryTags := make([]models.TagResponse, 0, len(selectedTagsNames)+len(excludedTagsNames))
+	if len(selectedTagsNames) > 0 || len(excludedTagsNames) > 0 {
+		queryTags, err = service.tagRepository.ReadManyByNames(append(slices.Clone(selectedTagsNames), excludedTagsNames...))
 		if err != nil {
 			return response, fmt.Errorf("unable to get tags from database by names: %w", err)
 		}
 	}
 
 	// If search request donesn't contain querystring or tags we searching for all docs or using built query otherwise
-	var searchRequest *bleve.SearchRequest
-	if len(searchQuery.Tags) == 0 && searchQuery.Query == "" {
-		searchRequest = bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), searchQuery.PageSize, searchQuery.PageNumber*searchQuery.PageSize, false)
-	} else {
-		searchRequest = bleve.NewSearchRequestOptions(booleanQuery, searchQuery.PageSize, searchQuery.PageNumber*searchQuery.PageSize, false)
+	var documentsQuery query.Query = booleanQuery
+	if len(searchQuery.Tags) == 0 && filter == nil && len(dateRangeQueries) == 0 && searchQuery.Query == "" {
+		documentsQuery = bleve.NewMatchAllQuery()
+	}
+	if profile != nil {
+		documentsQuery = profile.apply(documentsQuery, matchQuery, now)
+	}
+	searchRequest := bleve.NewSearchRequestOptions(documentsQuery, searchQuery.PageSize, searchQuery.PageNumber*searchQuery.PageSize, false)
+	searchRequest.SortByCustom(sortOrder)
+
+	// Page after cursor is found with SearchAfter instead of skipping previous pages.
+	// One extra hit is requested to know whether there is next page.
+	if searchAfter != nil {
+		searchRequest.From = 0
+		searchRequest.Size = searchQuery.PageSize + 1
+		searchRequest.SetSearchAfter(searchAfter)
 	}
 
 	// Getting all tags list to get tags quantity for facet request
@@ -104,7 +339,20 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 	}
 
 	// Adding facet request to include all tags in response
-	searchRequest.AddFacet("tags", bleve.NewFacetRequest("tags", len(allDbTags)))
+	searchRequest.AddFacet(tagsField, bleve.NewFacetRequest(tagsField, len(allDbTags)))
+	searchRequest.AddFacet(rollupTagsField, bleve.NewFacetRequest(rollupTagsField, len(allDbTags)))
+
+	// Adding date histogram facet, its buckets depend on dates of matched documents
+	var dateHistogram []DateBucket
+	if searchQuery.DateHistogram != nil {
+		dateHistogram, err = service.addDateHistogram(searchRequest, searchQuery.DateHistogram)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	// Term locations are required by highlighter to find fragments
+	searchRequest.IncludeLocations = searchQuery.Highlight != nil
 
 	// Getting search results with using search request
 	results, err := service.index.Search(searchRequest)
@@ -112,17 +360,42 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 		return response, err
 	}
 	response.DocumentsFound = int64(results.Total)
+	response.DateHistogram = fillDateHistogram(dateHistogram, results)
 
-	// Getting number of pages for result
-	pages := int(math.Ceil(float64(results.Total) / float64(searchQuery.PageSize)))
-	response.Pages = pages
+	hasNextPage := false
+	if searchAfter != nil {
+		// Position of page after cursor is unknown, only presence of next page is reported
+		hasNextPage = len(results.Hits) > searchQuery.PageSize
+		if hasNextPage {
+			results.Hits = results.Hits[:searchQuery.PageSize]
+		}
+		response.TotalPages = paginate(0, searchQuery.PageSize, results.Total).totalPages
+		response.HasPrev = true
+	} else {
+		page := paginate(searchQuery.PageNumber, searchQuery.PageSize, results.Total)
 
-	// If requested page is out of bounds change it to max page of search result
-	// and tell frontend to change its page. Also update search results for new page number
-	if searchQuery.PageNumber >= pages {
-		response.RequestPageIsOutOfBounds = true
-		searchRequest.From = pages
-		results, err = service.index.Search(searchRequest)
+		// If requested page is out of bounds change it to the last page of search result
+		// and tell frontend to change its page. Also update search results for new page number
+		if page.outOfBounds {
+			response.RequestPageIsOutOfBounds = true
+			searchRequest.From = page.offset
+			results, err = service.index.Search(searchRequest)
+			if err != nil {
+				return response, err
+			}
+		}
+
+		hasNextPage = page.hasNext()
+		response.Page = page.page + 1
+		response.TotalPages = page.totalPages
+		response.HasPrev = page.hasPrev()
+	}
+	response.Pages = response.TotalPages
+	response.PageSize = searchQuery.PageSize
+	response.HasNext = hasNextPage
+
+	if hasNextPage && len(results.Hits) > 0 {
+		response.NextCursor, err = encodeCursor(sortOrderStrings, sortOrder, results.Hits[len(results.Hits)-1], now)
 		if err != nil {
 			return response, err
 		}
@@ -139,10 +412,17 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 	if err != nil {
 		return response, fmt.Errorf("unable to ReadMany documents by IDs: %w", err)
 	}
-	response.Documents = foundDocuments
+	response.Documents = orderDocuments(foundDocuments, IDs)
+
+	if searchQuery.Highlight != nil {
+		response.Highlights, err = service.highlight(results.Hits, searchQuery.Highlight)
+		if err != nil {
+			return response, fmt.Errorf("unable to highlight search results: %w", err)
+		}
+	}
 
 	// Getting all tags with count from documents found by query
-	terms := results.Facets["tags"].Terms.Terms()
+	terms := results.Facets[tagsField].Terms.Terms()
 	foundTagsCount := make(map[TagName]DocumentCount, len(allDbTags))
 	foundTagsNames := make([]TagName, 0, len(allDbTags))
 	for _, term := range terms {
@@ -150,6 +430,13 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 		foundTagsNames = append(foundTagsNames, term.Term)
 	}
 
+	// Getting count of documents per tag including documents with descendant tags
+	rollupTerms := results.Facets[rollupTagsField].Terms.Terms()
+	foundRollupTagsCount := make(map[TagName]DocumentCount, len(rollupTerms))
+	for _, term := range rollupTerms {
+		foundRollupTagsCount[term.Term] = term.Count
+	}
+
 	// Getting additional metadata for tags from database
 	tagResponses, err := service.tagRepository.ReadManyByNames(foundTagsNames)
 	if err != nil {
@@ -157,28 +444,33 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 	}
 	for _, tag := range tagResponses {
 		response.Tags = append(response.Tags, TagBucket{
-			TagResponse:   tag,
-			DocumentCount: foundTagsCount[tag.Name],
-			Selected:      slices.Contains(searchQuery.Tags, tag.Name),
+			TagResponse:         tag,
+			DocumentCount:       foundTagsCount[tag.Name],
+			RollupDocumentCount: foundRollupTagsCount[tag.Name],
+			Selected:            slices.Contains(selectedTagsNames, tag.Name),
+			Excluded:            slices.Contains(excludedTagsNames, tag.Name),
 		})
 	}
 
-	// Adding tags without any documents assigned to response
+	// Adding tags without any documents assigned to response. They still may have documents assigned to descendants
 	for _, tag := range allDbTags {
 		if !tag.Assigned {
 			response.Tags = append(response.Tags, TagBucket{
-				TagResponse: tag,
+				TagResponse:         tag,
+				RollupDocumentCount: foundRollupTagsCount[tag.Name],
 			})
 		}
 	}
 
-	// Adding selected tags with zero document count to response
+	// Adding selected and excluded tags with zero document count to response
 	for _, tag := range queryTags {
 		if !slices.Contains(tagResponses, tag) {
 			response.Tags = append(response.Tags, TagBucket{
-				TagResponse:   tag,
-				DocumentCount: 0,
-				Selected:      true,
+				TagResponse:         tag,
+				DocumentCount:       0,
+				RollupDocumentCount: foundRollupTagsCount[tag.Name],
+				Selected:            slices.Contains(selectedTagsNames, tag.Name),
+				Excluded:            slices.Contains(excludedTagsNames, tag.Name),
 			})
 		}
 	}
@@ -186,20 +478,146 @@ func (service *IndexService) Find(searchQuery *SearchDocumentRequest) (response
 	return response, nil
 }
 
+// Replaces aliases with canonical tag names in passed tags and in tag filter
+func (service *IndexService) resolveAliases(tags []TagName, filter *tagFilter) (resolvedTags []TagName, err error) {
+	names := slices.Clone(tags)
+	if filter != nil {
+		included, excluded := filter.tags()
+		names = append(append(names, included...), excluded...)
+	}
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	canonicalTags, err := service.tagRepository.ResolveAliases(names)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve tag aliases: %w", err)
+	}
+	if len(canonicalTags) == 0 {
+		return tags, nil
+	}
+
+	replacements := make(map[string]string, len(canonicalTags))
+	for alias, tag := range canonicalTags {
+		replacements[alias] = tag.Name
+	}
+
+	resolvedTags = make([]TagName, 0, len(tags))
+	for _, tag := range tags {
+		if replacement, ok := replacements[tag]; ok {
+			tag = replacement
+		}
+		resolvedTags = append(resolvedTags, tag)
+	}
+
+	if filter != nil {
+		filter.replaceTags(replacements)
+	}
+
+	return resolvedTags, nil
+}
+
+// Returns documents in order of passed IDs because database returns them in arbitrary order
+func orderDocuments(documents []models.DocumentResponse, IDs []models.ID) []models.DocumentResponse {
+	if len(documents) == 0 {
+		return documents
+	}
+
+	documentsByID := make(map[models.ID]models.DocumentResponse, len(documents))
+	for _, document := range documents {
+		documentsByID[document.ID] = document
+	}
+
+	ordered := make([]models.DocumentResponse, 0, len(documents))
+	for _, id := range IDs {
+		if document, ok := documentsByID[id]; ok {
+			ordered = append(ordered, document)
+		}
+	}
+	return ordered
+}
+
+// Collects highlighted fragments for every search hit that has matched terms
+func (service *IndexService) highlight(hits search.DocumentMatchCollection, request *HighlightRequest) (map[models.ID]Highlight, error) {
+	highlighter, err := request.highlighter()
+	if err != nil {
+		return nil, err
+	}
+
+	highlights := make(map[models.ID]Highlight, len(hits))
+	for _, match := range hits {
+		if len(match.Locations) == 0 {
+			continue
+		}
+
+		document, err := service.index.Document(match.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get document '%s' from index: %w", match.ID, err)
+		}
+		if document == nil {
+			continue
+		}
+
+		id, err := strconv.ParseInt(match.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse document id '%s': %w", match.ID, err)
+		}
+
+		if documentHighlight := highlightMatch(highlighter, withLanguageLocations(match), document, request.Fragments); len(documentHighlight) > 0 {
+			highlights[id] = documentHighlight
+		}
+	}
+
+	return highlights, nil
+}
+
 // Perform batch document indexing or update
 func (service *IndexService) Index(documents []models.DocumentResponse) error {
-	batch := service.index.NewBatch()
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if err := service.indexDocuments(service.index, documents); err != nil {
+		return err
+	}
+
+	IDs := make([]models.ID, 0, len(documents))
 	for _, document := range documents {
-		batch.Index(
-			fmt.Sprint(document.ID),
-			IndexDocument{
-				Name: document.Name,
-				Body: document.Body,
-				Tags: document.TagNames(),
-			},
-		)
+		IDs = append(IDs, document.ID)
 	}
-	return service.index.Batch(batch)
+	service.markRebuildDirty(IDs)
+
+	return nil
+}
+
+func (service *IndexService) indexDocuments(index bleve.Index, documents []models.DocumentResponse) error {
+	// Tags hierarchy is needed to find ancestors of documents tags
+	tags, err := service.tagRepository.List()
+	if err != nil {
+		return fmt.Errorf("unable to get List of all tags: %w", err)
+	}
+	tagsByID := make(map[models.ID]models.TagResponse, len(tags))
+	for _, tag := range tags {
+		tagsByID[tag.ID] = tag
+	}
+
+	batch := index.NewBatch()
+	for _, document := range documents {
+		indexDocument := IndexDocument{
+			ID:          document.ID,
+			Name:        document.Name,
+			Body:        document.Body,
+			Language:    documentLanguage(document),
+			Tags:        document.TagNames(),
+			TagsCount:   len(document.Tags),
+			TagsSuggest: tagSuggestTerms(document.Tags),
+			RollupTags:  rollupTags(document.Tags, tagsByID),
+			CreatedAt:   document.CreatedAt,
+			UpdatedAt:   document.UpdatedAt,
+		}
+		indexDocument.setLanguageFields()
+		batch.Index(fmt.Sprint(document.ID), indexDocument)
+	}
+	return index.Batch(batch)
 	// for _, document := range documents {
 	// 	service.index.Index(
 	// 		fmt.Sprint(document.ID),
@@ -212,12 +630,48 @@ func (service *IndexService) Index(documents []models.DocumentResponse) error {
 	// return nil
 }
 
+// Returns unique names of passed tags and all of their ancestors
+func rollupTags(documentTags []models.TagResponse, tagsByID map[models.ID]models.TagResponse) (names []string) {
+	seen := make(map[models.ID]struct{}, len(documentTags))
+	for _, tag := range documentTags {
+		for {
+			if _, ok := seen[tag.ID]; ok {
+				break
+			}
+			seen[tag.ID] = struct{}{}
+			names = append(names, tag.Name)
+
+			if !tag.ParentID.Valid {
+				break
+			}
+			parent, ok := tagsByID[tag.ParentID.Int64]
+			if !ok {
+				break
+			}
+			tag = parent
+		}
+	}
+	return names
+}
+
 func (service *IndexService) Delete(IDs []models.ID) error {
-	batch := service.index.NewBatch()
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if err := deleteDocuments(service.index, IDs); err != nil {
+		return err
+	}
+	service.markRebuildDirty(IDs)
+
+	return nil
+}
+
+func deleteDocuments(index bleve.Index, IDs []models.ID) error {
+	batch := index.NewBatch()
 	for _, ID := range IDs {
 		batch.Delete(
 			fmt.Sprint(ID),
 		)
 	}
-	return service.index.Batch(batch)
+	return index.Batch(batch)
 }
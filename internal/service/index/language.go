@@ -0,0 +1,165 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Analyzers of language specific fields
+var languageAnalyzers = map[string]string{
+	models.LanguageRussian: ru.AnalyzerName,
+	models.LanguageEnglish: en.AnalyzerName,
+}
+
+// Fields which are indexed separately for every language
+var analyzedFields = []string{nameField, bodyField}
+
+// Returns name of field analyzed for language, e.g. `name_ru`
+func analyzedField(field string, language string) string {
+	return field + "_" + language
+}
+
+// Returns language fields of every language where field is searched
+func searchedFields(field string) (fields []string) {
+	for _, language := range models.Languages {
+		if field == "" {
+			for _, fieldName := range analyzedFields {
+				fields = append(fields, analyzedField(fieldName, language))
+			}
+		} else {
+			fields = append(fields, analyzedField(field, language))
+		}
+	}
+	return fields
+}
+
+// Language of document which was created before languages were introduced is detected from its body
+func documentLanguage(document models.DocumentResponse) string {
+	if models.IsSupportedLanguage(document.Language) {
+		return document.Language
+	}
+	return models.DetectLanguage(document.Body)
+}
+
+// Fills fields of document language, fields of other languages are left empty
+func (indexDocument *IndexDocument) setLanguageFields() {
+	switch indexDocument.Language {
+	case models.LanguageRussian:
+		indexDocument.NameRu, indexDocument.BodyRu = indexDocument.Name, indexDocument.Body
+	case models.LanguageEnglish:
+		indexDocument.NameEn, indexDocument.BodyEn = indexDocument.Name, indexDocument.Body
+	}
+}
+
+/*
+Replaces queries of parsed query string which search name, body or default field
+by disjunction of the same query over language fields, so each query term is analyzed
+by analyzer of every language. Query of default field is kept in disjunction
+because default field contains tags.
+*/
+func expandLanguageFields(q query.Query) query.Query {
+	switch q := q.(type) {
+	case *query.BooleanQuery:
+		q.Must = expandLanguageFields(q.Must)
+		q.Should = expandLanguageFields(q.Should)
+		q.MustNot = expandLanguageFields(q.MustNot)
+	case *query.ConjunctionQuery:
+		for i, conjunct := range q.Conjuncts {
+			q.Conjuncts[i] = expandLanguageFields(conjunct)
+		}
+	case *query.DisjunctionQuery:
+		for i, disjunct := range q.Disjuncts {
+			q.Disjuncts[i] = expandLanguageFields(disjunct)
+		}
+	case query.FieldableQuery:
+		field := q.Field()
+		if field != "" && field != nameField && field != bodyField {
+			return q
+		}
+
+		var disjuncts []query.Query
+		if field == "" {
+			disjuncts = append(disjuncts, q)
+		}
+		for _, fieldName := range searchedFields(field) {
+			if fieldQuery := queryWithField(q, fieldName); fieldQuery != nil {
+				disjuncts = append(disjuncts, fieldQuery)
+			}
+		}
+		if len(disjuncts) == 1 {
+			return disjuncts[0]
+		}
+		return query.NewDisjunctionQuery(disjuncts)
+	}
+	return q
+}
+
+// Returns copy of query which searches passed field or nil if query type is not supported
+func queryWithField(q query.FieldableQuery, field string) query.FieldableQuery {
+	var fieldQuery query.FieldableQuery
+	switch q := q.(type) {
+	case *query.MatchQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.MatchPhraseQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.WildcardQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.RegexpQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.FuzzyQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.PrefixQuery:
+		copied := *q
+		fieldQuery = &copied
+	case *query.TermQuery:
+		copied := *q
+		fieldQuery = &copied
+	default:
+		return nil
+	}
+	fieldQuery.SetField(field)
+	return fieldQuery
+}
+
+/*
+Returns copy of match with term locations of language fields moved to fields they were analyzed from.
+Language fields are not stored, their text is the same as text of stored field, so locations
+of language fields point to the same fragments of stored field.
+*/
+func withLanguageLocations(match *search.DocumentMatch) *search.DocumentMatch {
+	locations := make(search.FieldTermLocationMap, len(match.Locations))
+	for field, termLocations := range match.Locations {
+		locations[field] = termLocations
+	}
+
+	for _, field := range analyzedFields {
+		for _, language := range models.Languages {
+			termLocations, ok := match.Locations[analyzedField(field, language)]
+			if !ok {
+				continue
+			}
+			merged := make(search.TermLocationMap, len(locations[field])+len(termLocations))
+			for term, termLocation := range locations[field] {
+				merged[term] = termLocation
+			}
+			for term, termLocation := range termLocations {
+				merged[term] = append(slices.Clip(merged[term]), termLocation...)
+			}
+			locations[field] = merged
+		}
+	}
+
+	copied := *match
+	copied.Locations = locations
+	return &copied
+}
@@ -1,28 +1,189 @@
 package service
 
 import (
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/blevesearch/bleve/v2"
-	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/token/edgengram"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/truncate"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
 	"github.com/blevesearch/bleve/v2/mapping"
 )
 
+// Names of index document fields
+const (
+	idField          = "id"
+	nameField        = "name"
+	nameSortField    = "nameSort"
+	nameSuggestField = "nameSuggest"
+	bodyField        = "body"
+	languageField    = "language"
+	tagsField        = "tags"
+	tagsSuggestField = "tagsSuggest"
+	rollupTagsField  = "rollupTags"
+	tagsCountField   = "tagsCount"
+	createdAtField   = "createdAt"
+	updatedAtField   = "updatedAt"
+)
+
+// Analyzer which keeps whole lowercased value as single token. Used for sorting and tag suggestions.
+const sortableAnalyzerName = "sortable"
+
+// Analyzer which splits text into lowercased words without stemming. Used for spelling correction.
+const spellingAnalyzerName = "spelling"
+
+// Analyzers of document name suggestions
+const (
+	// Indexes lowercased prefixes of every word
+	suggestAnalyzerName = "suggest"
+	// Analyzes typed prefix, its words are kept whole to match indexed prefixes
+	suggestQueryAnalyzerName = "suggestQuery"
+
+	suggestPrefixFilterName   = "suggestPrefix"
+	suggestTruncateFilterName = "suggestTruncate"
+	// Words longer than this are suggested by their first runes
+	maxSuggestPrefixLength = 20
+)
+
 func GetIndexMapping() *mapping.IndexMappingImpl {
 	indexMapping := bleve.NewIndexMapping()
+	err := indexMapping.AddCustomAnalyzer(sortableAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = indexMapping.AddCustomAnalyzer(spellingAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if err := addSuggestAnalyzers(indexMapping); err != nil {
+		panic(err)
+	}
+
 	documentMapping := bleve.NewDocumentMapping()
 
+	documentIDFieldMapping := bleve.NewNumericFieldMapping()
+	documentIDFieldMapping.IncludeInAll = false
+	documentMapping.AddFieldMappingsAt(idField, documentIDFieldMapping)
+
+	// Name and body are stored for highlighting and verification and are searched with language fields.
+	// Their terms are whole lowercased words used as dictionary for spelling correction.
 	documentNameFieldMapping := bleve.NewTextFieldMapping()
-	documentNameFieldMapping.Analyzer = ru.AnalyzerName
-	documentMapping.AddFieldMappingsAt("name", documentNameFieldMapping)
+	documentNameFieldMapping.Analyzer = spellingAnalyzerName
+	documentNameFieldMapping.IncludeInAll = false
+	documentNameFieldMapping.IncludeTermVectors = false
+	documentNameFieldMapping.DocValues = false
+	documentNameSortFieldMapping := bleve.NewTextFieldMapping()
+	documentNameSortFieldMapping.Name = nameSortField
+	documentNameSortFieldMapping.Analyzer = sortableAnalyzerName
+	documentNameSortFieldMapping.Store = false
+	documentNameSortFieldMapping.IncludeInAll = false
+	documentNameSortFieldMapping.IncludeTermVectors = false
+	documentNameSuggestFieldMapping := bleve.NewTextFieldMapping()
+	documentNameSuggestFieldMapping.Name = nameSuggestField
+	documentNameSuggestFieldMapping.Analyzer = suggestAnalyzerName
+	documentNameSuggestFieldMapping.Store = false
+	documentNameSuggestFieldMapping.IncludeInAll = false
+	documentNameSuggestFieldMapping.IncludeTermVectors = false
+	documentNameSuggestFieldMapping.DocValues = false
+	documentMapping.AddFieldMappingsAt(nameField, documentNameFieldMapping, documentNameSortFieldMapping, documentNameSuggestFieldMapping)
 
 	documentBodyFieldMapping := bleve.NewTextFieldMapping()
-	documentNameFieldMapping.Analyzer = ru.AnalyzerName
-	documentMapping.AddFieldMappingsAt("body", documentBodyFieldMapping)
+	documentBodyFieldMapping.Analyzer = spellingAnalyzerName
+	documentBodyFieldMapping.IncludeInAll = false
+	documentBodyFieldMapping.IncludeTermVectors = false
+	documentBodyFieldMapping.DocValues = false
+	documentMapping.AddFieldMappingsAt(bodyField, documentBodyFieldMapping)
+
+	documentLanguageFieldMapping := bleve.NewKeywordFieldMapping()
+	documentLanguageFieldMapping.IncludeInAll = false
+	documentMapping.AddFieldMappingsAt(languageField, documentLanguageFieldMapping)
+
+	// Name and body analyzed with analyzer of document language
+	for _, language := range models.Languages {
+		for _, field := range analyzedFields {
+			documentLanguageTextFieldMapping := bleve.NewTextFieldMapping()
+			documentLanguageTextFieldMapping.Analyzer = languageAnalyzers[language]
+			documentLanguageTextFieldMapping.Store = false
+			documentLanguageTextFieldMapping.IncludeInAll = false
+			documentMapping.AddFieldMappingsAt(analyzedField(field, language), documentLanguageTextFieldMapping)
+		}
+	}
 
 	documentTagsFieldMapping := bleve.NewKeywordFieldMapping()
-	documentMapping.AddFieldMappingsAt("tags", documentTagsFieldMapping)
+	documentMapping.AddFieldMappingsAt(tagsField, documentTagsFieldMapping)
+
+	// Terms of tags looked up by prefix for suggestions, see tagSuggestTerms
+	documentTagsSuggestFieldMapping := bleve.NewKeywordFieldMapping()
+	documentTagsSuggestFieldMapping.Store = false
+	documentTagsSuggestFieldMapping.IncludeInAll = false
+	documentTagsSuggestFieldMapping.IncludeTermVectors = false
+	documentTagsSuggestFieldMapping.DocValues = false
+	documentMapping.AddFieldMappingsAt(tagsSuggestField, documentTagsSuggestFieldMapping)
+
+	documentRollupTagsFieldMapping := bleve.NewKeywordFieldMapping()
+	documentRollupTagsFieldMapping.Store = false
+	documentRollupTagsFieldMapping.IncludeInAll = false
+	documentMapping.AddFieldMappingsAt(rollupTagsField, documentRollupTagsFieldMapping)
+
+	documentTagsCountFieldMapping := bleve.NewNumericFieldMapping()
+	documentTagsCountFieldMapping.IncludeInAll = false
+	documentMapping.AddFieldMappingsAt(tagsCountField, documentTagsCountFieldMapping)
+
+	for _, field := range []string{createdAtField, updatedAtField} {
+		documentDateFieldMapping := bleve.NewDateTimeFieldMapping()
+		documentDateFieldMapping.IncludeInAll = false
+		documentMapping.AddFieldMappingsAt(field, documentDateFieldMapping)
+	}
 
 	indexMapping.DefaultMapping = documentMapping
-	indexMapping.DefaultAnalyzer = ru.AnalyzerName
+	indexMapping.DefaultAnalyzer = standard.Name
 
 	return indexMapping
 }
+
+func addSuggestAnalyzers(indexMapping *mapping.IndexMappingImpl) error {
+	err := indexMapping.AddCustomTokenFilter(suggestPrefixFilterName, map[string]interface{}{
+		"type": edgengram.Name,
+		"min":  1.0,
+		"max":  float64(maxSuggestPrefixLength),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = indexMapping.AddCustomTokenFilter(suggestTruncateFilterName, map[string]interface{}{
+		"type":   truncate.Name,
+		"length": float64(maxSuggestPrefixLength),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = indexMapping.AddCustomAnalyzer(suggestAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, suggestPrefixFilterName},
+	})
+	if err != nil {
+		return err
+	}
+
+	return indexMapping.AddCustomAnalyzer(suggestQueryAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, suggestTruncateFilterName},
+	})
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// Key of index internal metadata where hash of mapping index was built with is stored
+var mappingHashKey = []byte("mappingHash")
+
+var (
+	ErrMappingChanged = errors.New("index was built with different mapping")
+)
+
+// Returns hash of serialized GetIndexMapping result. Any change of mapping changes hash.
+func MappingHash() (string, error) {
+	serialized, err := json.Marshal(GetIndexMapping())
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize index mapping: %w", err)
+	}
+	hash := sha256.Sum256(serialized)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Creates index with current mapping at path and stores mapping hash in it
+func NewIndex(path string) (bleve.Index, error) {
+	index, err := bleve.New(path, GetIndexMapping())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := StoreMappingHash(index); err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func StoreMappingHash(index bleve.Index) error {
+	hash, err := MappingHash()
+	if err != nil {
+		return err
+	}
+	return index.SetInternal(mappingHashKey, []byte(hash))
+}
+
+/*
+Returns wrapped ErrMappingChanged if index was built with mapping other than current one.
+Indexes built before mapping hash was introduced have no hash and are treated as changed.
+*/
+func CheckMappingHash(index bleve.Index) error {
+	hash, err := MappingHash()
+	if err != nil {
+		return err
+	}
+
+	storedHash, err := index.GetInternal(mappingHashKey)
+	if err != nil {
+		return fmt.Errorf("unable to read index mapping hash: %w", err)
+	}
+	if storedHash == nil {
+		return fmt.Errorf("%w: index has no mapping hash", ErrMappingChanged)
+	}
+	if !bytes.Equal(storedHash, []byte(hash)) {
+		return fmt.Errorf("%w: index mapping hash '%s', current mapping hash '%s'", ErrMappingChanged, storedHash, hash)
+	}
+
+	return nil
+}
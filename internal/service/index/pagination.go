@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Page size used by search endpoint if it is not passed
+	DefaultPageSize = 10
+	// Search requests with bigger page size are rejected, deep result sets should be walked with cursor
+	DefaultMaxPageSize = 100
+)
+
+var (
+	ErrInvalidPagination = errors.New("invalid pagination")
+)
+
+// Position of requested page within search results
+type pagination struct {
+	page       int // zero based, clamped to the last page
+	offset     int // index of first hit of page
+	totalPages int
+	// Requested page is after the last one, page is clamped to the last page then
+	outOfBounds bool
+}
+
+/*
+Sets default page size if it is zero. Returns error wrapping ErrInvalidPagination
+if page size is out of [1, maxPageSize] or page number is negative.
+*/
+func (searchQuery *SearchDocumentRequest) validatePagination(maxPageSize int) error {
+	if searchQuery.PageSize == 0 {
+		searchQuery.PageSize = DefaultPageSize
+	}
+	if searchQuery.PageSize < 1 || searchQuery.PageSize > maxPageSize {
+		return fmt.Errorf("%w: page size must be between 1 and %d, got %d", ErrInvalidPagination, maxPageSize, searchQuery.PageSize)
+	}
+	if searchQuery.PageNumber < 0 {
+		return fmt.Errorf("%w: page number must not be negative, got %d", ErrInvalidPagination, searchQuery.PageNumber)
+	}
+	return nil
+}
+
+/*
+Returns position of page with passed zero based number among total hits.
+Page after the last one is clamped to the last page, empty results have zero pages and the only page at offset 0.
+*/
+func paginate(pageNumber, pageSize int, total uint64) pagination {
+	totalPages := int((total + uint64(pageSize) - 1) / uint64(pageSize))
+
+	result := pagination{page: pageNumber, totalPages: totalPages}
+	if pageNumber >= totalPages && pageNumber > 0 {
+		result.outOfBounds = true
+		result.page = max(totalPages-1, 0)
+	}
+	result.offset = result.page * pageSize
+	return result
+}
+
+func (p pagination) hasNext() bool {
+	return p.page < p.totalPages-1
+}
+
+func (p pagination) hasPrev() bool {
+	return p.page > 0
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/blevesearch/bleve/v2/search/searcher"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+/*
+Phrase query whose terms may be moved from their places in phrase. Slop is the maximum
+sum of distances between positions of found terms and positions they would have in exact phrase.
+Bleve phrase query supports only exact phrases, so terms are found with conjunction searcher
+and their positions are checked by proximitySearcher.
+*/
+type proximityQuery struct {
+	phrase    string
+	field     string
+	fuzziness int
+	slop      int
+	boost     *query.Boost
+}
+
+// Term of phrase with its offset from the first phrase term. Offsets of terms after removed stop words have gaps.
+type phraseTerm struct {
+	offset int
+	// Index terms matching phrase term, more than one if phrase is fuzzy
+	terms []string
+}
+
+func newProximityQuery(phrase string, field string, fuzziness int, slop int) *proximityQuery {
+	return &proximityQuery{
+		phrase:    phrase,
+		field:     field,
+		fuzziness: fuzziness,
+		slop:      slop,
+	}
+}
+
+func (q *proximityQuery) SetBoost(b float64) {
+	boost := query.Boost(b)
+	q.boost = &boost
+}
+
+func (q *proximityQuery) Boost() float64 {
+	return q.boost.Value()
+}
+
+func (q *proximityQuery) Field() string {
+	return q.field
+}
+
+func (q *proximityQuery) SetField(f string) {
+	q.field = f
+}
+
+func (q *proximityQuery) Searcher(ctx context.Context, reader index.IndexReader, indexMapping mapping.IndexMapping, options search.SearcherOptions) (search.Searcher, error) {
+	analyzerName := indexMapping.AnalyzerNameForPath(q.field)
+	analyzer := indexMapping.AnalyzerNamed(analyzerName)
+	if analyzer == nil {
+		return nil, fmt.Errorf("no analyzer named '%s' registered", analyzerName)
+	}
+
+	tokens := analyzer.Analyze([]byte(q.phrase))
+	if len(tokens) == 0 {
+		return query.NewMatchNoneQuery().Searcher(ctx, reader, indexMapping, options)
+	}
+
+	phraseTerms := make([]phraseTerm, 0, len(tokens))
+	for _, token := range tokens {
+		terms := []string{string(token.Term)}
+		if q.fuzziness > 0 {
+			closeTerms := map[string]uint64{string(token.Term): 0}
+			if err := collectCloseTerms(reader, q.field, string(token.Term), q.fuzziness, closeTerms); err != nil {
+				return nil, err
+			}
+			terms = terms[:0]
+			for term := range closeTerms {
+				terms = append(terms, term)
+			}
+		}
+		phraseTerms = append(phraseTerms, phraseTerm{offset: token.Position - tokens[0].Position, terms: terms})
+	}
+
+	// Positions of terms are required to check their distances
+	options.IncludeTermVectors = true
+	termsSearchers := make([]search.Searcher, 0, len(phraseTerms))
+	closeSearchers := func() {
+		for _, termsSearcher := range termsSearchers {
+			termsSearcher.Close()
+		}
+	}
+	for _, phraseTerm := range phraseTerms {
+		termSearchers := make([]search.Searcher, 0, len(phraseTerm.terms))
+		for _, term := range phraseTerm.terms {
+			termSearcher, err := searcher.NewTermSearcher(ctx, reader, term, q.field, q.boost.Value(), options)
+			if err != nil {
+				for _, termSearcher := range termSearchers {
+					termSearcher.Close()
+				}
+				closeSearchers()
+				return nil, err
+			}
+			termSearchers = append(termSearchers, termSearcher)
+		}
+
+		termsSearcher, err := searcher.NewDisjunctionSearcher(ctx, reader, termSearchers, 1, options)
+		if err != nil {
+			closeSearchers()
+			return nil, err
+		}
+		termsSearchers = append(termsSearchers, termsSearcher)
+	}
+
+	conjunctionSearcher, err := searcher.NewConjunctionSearcher(ctx, reader, termsSearchers, options)
+	if err != nil {
+		closeSearchers()
+		return nil, err
+	}
+
+	return &proximitySearcher{Searcher: conjunctionSearcher, phraseTerms: phraseTerms, slop: q.slop}, nil
+}
+
+// Returns only documents found by embedded conjunction searcher whose terms are close enough
+type proximitySearcher struct {
+	search.Searcher
+	phraseTerms []phraseTerm
+	slop        int
+}
+
+func (s *proximitySearcher) Next(ctx *search.SearchContext) (*search.DocumentMatch, error) {
+	for {
+		match, err := s.Searcher.Next(ctx)
+		if err != nil || match == nil {
+			return match, err
+		}
+		if s.isClose(match) {
+			return match, nil
+		}
+		ctx.DocumentMatchPool.Put(match)
+	}
+}
+
+func (s *proximitySearcher) Advance(ctx *search.SearchContext, ID index.IndexInternalID) (*search.DocumentMatch, error) {
+	match, err := s.Searcher.Advance(ctx, ID)
+	if err != nil || match == nil {
+		return match, err
+	}
+	if s.isClose(match) {
+		return match, nil
+	}
+	ctx.DocumentMatchPool.Put(match)
+	return s.Next(ctx)
+}
+
+// Checks that positions of phrase terms in matched document can be chosen within slop
+func (s *proximitySearcher) isClose(match *search.DocumentMatch) bool {
+	termPositions := make(map[string][]uint64, len(match.FieldTermLocations))
+	for _, location := range match.FieldTermLocations {
+		termPositions[location.Term] = append(termPositions[location.Term], location.Location.Pos)
+	}
+
+	phrasePositions := make([][]uint64, len(s.phraseTerms))
+	for i, phraseTerm := range s.phraseTerms {
+		for _, term := range phraseTerm.terms {
+			phrasePositions[i] = append(phrasePositions[i], termPositions[term]...)
+		}
+	}
+
+	chosen := make([]uint64, 0, len(s.phraseTerms))
+	var choose func(i int, remainingSlop int) bool
+	choose = func(i int, remainingSlop int) bool {
+		if i == len(s.phraseTerms) {
+			return true
+		}
+	POSITIONS:
+		for _, position := range phrasePositions[i] {
+			for _, chosenPosition := range chosen {
+				if chosenPosition == position {
+					continue POSITIONS
+				}
+			}
+
+			distance := 0
+			if i > 0 {
+				expected := int(chosen[i-1]) + s.phraseTerms[i].offset - s.phraseTerms[i-1].offset
+				distance = max(expected-int(position), int(position)-expected)
+			}
+			if distance > remainingSlop {
+				continue
+			}
+
+			chosen = append(chosen, position)
+			if choose(i+1, remainingSlop-distance) {
+				return true
+			}
+			chosen = chosen[:len(chosen)-1]
+		}
+		return false
+	}
+
+	return choose(0, s.slop)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	QueryOperatorOr  = "or"
+	QueryOperatorAnd = "and"
+
+	// Bleve fuzzy search supports at most two edits
+	MaxFuzziness = 2
+	MaxSlop      = 10
+)
+
+var (
+	ErrInvalidQueryOptions = errors.New("invalid query options")
+)
+
+// Query is searched as plain text with options instead of query string syntax if any option is set
+func (request *SearchDocumentRequest) hasQueryOptions() bool {
+	return request.Fuzziness != 0 || request.Phrase || request.Slop != 0 || request.Operator != "" || len(request.Fields) > 0
+}
+
+// Checks that query options are within bounds and compatible with each other
+func (request *SearchDocumentRequest) validateQueryOptions() error {
+	if request.Fuzziness < 0 || request.Fuzziness > MaxFuzziness {
+		return fmt.Errorf("%w: fuzziness must be between 0 and %d", ErrInvalidQueryOptions, MaxFuzziness)
+	}
+
+	if request.Slop < 0 || request.Slop > MaxSlop {
+		return fmt.Errorf("%w: slop must be between 0 and %d", ErrInvalidQueryOptions, MaxSlop)
+	}
+	if request.Slop > 0 && !request.Phrase {
+		return fmt.Errorf("%w: slop is allowed only for phrase", ErrInvalidQueryOptions)
+	}
+
+	switch request.Operator {
+	case "", QueryOperatorOr, QueryOperatorAnd:
+	default:
+		return fmt.Errorf("%w: unknown operator '%s', expected '%s' or '%s'", ErrInvalidQueryOptions, request.Operator, QueryOperatorOr, QueryOperatorAnd)
+	}
+	if request.Operator != "" && request.Phrase {
+		return fmt.Errorf("%w: operator is not allowed for phrase", ErrInvalidQueryOptions)
+	}
+
+	for i, field := range request.Fields {
+		if !slices.Contains(analyzedFields, field) {
+			return fmt.Errorf("%w: unknown field '%s', expected one of %v", ErrInvalidQueryOptions, field, analyzedFields)
+		}
+		if slices.Contains(request.Fields[:i], field) {
+			return fmt.Errorf("%w: field '%s' is passed more than once", ErrInvalidQueryOptions, field)
+		}
+	}
+
+	return nil
+}
+
+/*
+Builds query searching Query text in requested fields of every language.
+Phrase is searched in each field separately. Otherwise text is analyzed by analyzer of every language
+and each of resulting terms is searched in all requested fields of that language, so with `and` operator
+terms may be found in different fields. Language whose analyzer leaves no terms, e.g. because all words are stop words, is skipped.
+*/
+func (service *IndexService) optionsQuery(request *SearchDocumentRequest) query.Query {
+	fields := request.Fields
+	if len(fields) == 0 {
+		fields = analyzedFields
+	}
+
+	var languageQueries []query.Query
+	for _, language := range models.Languages {
+		if request.Phrase {
+			for _, field := range fields {
+				languageQueries = append(languageQueries, phraseQuery(request, analyzedField(field, language)))
+			}
+			continue
+		}
+
+		tokens := service.index.Mapping().AnalyzerNamed(languageAnalyzers[language]).Analyze([]byte(request.Query))
+		if len(tokens) == 0 {
+			continue
+		}
+
+		termQueries := make([]query.Query, 0, len(tokens))
+		for _, token := range tokens {
+			fieldQueries := make([]query.Query, 0, len(fields))
+			for _, field := range fields {
+				fieldQueries = append(fieldQueries, termQuery(string(token.Term), analyzedField(field, language), request.Fuzziness))
+			}
+			termQueries = append(termQueries, bleve.NewDisjunctionQuery(fieldQueries...))
+		}
+
+		if request.Operator == QueryOperatorAnd {
+			languageQueries = append(languageQueries, bleve.NewConjunctionQuery(termQueries...))
+		} else {
+			languageQueries = append(languageQueries, bleve.NewDisjunctionQuery(termQueries...))
+		}
+	}
+
+	if len(languageQueries) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	return bleve.NewDisjunctionQuery(languageQueries...)
+}
+
+// Returns exact phrase query or proximity query if slop is requested
+func phraseQuery(request *SearchDocumentRequest, field string) query.Query {
+	if request.Slop > 0 {
+		return newProximityQuery(request.Query, field, request.Fuzziness, request.Slop)
+	}
+	phraseQuery := bleve.NewMatchPhraseQuery(request.Query)
+	phraseQuery.SetField(field)
+	phraseQuery.SetFuzziness(request.Fuzziness)
+	return phraseQuery
+}
+
+// Returns query of already analyzed term, fuzzy if fuzziness is requested
+func termQuery(term string, field string, fuzziness int) query.Query {
+	if fuzziness > 0 {
+		fuzzyQuery := bleve.NewFuzzyQuery(term)
+		fuzzyQuery.SetField(field)
+		fuzzyQuery.SetFuzziness(fuzziness)
+		return fuzzyQuery
+	}
+	termQuery := bleve.NewTermQuery(term)
+	termQuery.SetField(field)
+	return termQuery
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+)
+
+// Number of documents read from database and indexed at once during rebuild
+const rebuildBatchSize = 1000
+
+var (
+	ErrRebuildInProgress = errors.New("index rebuild is already in progress")
+	ErrIndexPathRequired = errors.New("index path is required to rebuild index")
+)
+
+type RebuildStatus struct {
+	Running          bool       `json:"running"`
+	StartedAt        *time.Time `json:"startedAt,omitempty"`
+	FinishedAt       *time.Time `json:"finishedAt,omitempty"`
+	TotalDocuments   int        `json:"totalDocuments"`
+	IndexedDocuments int        `json:"indexedDocuments"`
+	Error            string     `json:"error,omitempty"`
+	// Current index could not be reopened after failed swap, searches fail until index is rebuilt or service is restarted
+	IndexUnavailable bool `json:"indexUnavailable,omitempty"`
+}
+
+/*
+Builds fresh index with current mapping from database documents and replaces index at path with it.
+
+New index is built in `<path>.new` directory while current index keeps serving requests.
+Documents which are changed during rebuild are reindexed into new index once more right before swap,
+so changes made while rebuild is running are not lost. After swap old index directory is removed.
+*/
+func (service *IndexService) Rebuild(documentRepository DocumentIDLister, path string) error {
+	if err := service.beginRebuild(path); err != nil {
+		return err
+	}
+	err := service.rebuild(documentRepository, path)
+	service.finishRebuild(err)
+	return err
+}
+
+// Same as Rebuild but runs in background, progress is available in RebuildStatus
+func (service *IndexService) StartRebuild(documentRepository DocumentIDLister, path string) error {
+	if err := service.beginRebuild(path); err != nil {
+		return err
+	}
+	go func() {
+		service.finishRebuild(service.rebuild(documentRepository, path))
+	}()
+	return nil
+}
+
+func (service *IndexService) RebuildStatus() RebuildStatus {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	return service.rebuildStatus
+}
+
+func (service *IndexService) beginRebuild(path string) error {
+	if path == "" {
+		return ErrIndexPathRequired
+	}
+
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	if service.rebuildStatus.Running {
+		return ErrRebuildInProgress
+	}
+
+	startedAt := time.Now()
+	service.rebuildStatus = RebuildStatus{Running: true, StartedAt: &startedAt, IndexUnavailable: service.rebuildStatus.IndexUnavailable}
+	service.rebuildDirty = map[models.ID]struct{}{}
+	return nil
+}
+
+func (service *IndexService) finishRebuild(err error) {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	finishedAt := time.Now()
+	service.rebuildStatus.Running = false
+	service.rebuildStatus.FinishedAt = &finishedAt
+	if err != nil {
+		service.rebuildStatus.Error = err.Error()
+	}
+	service.rebuildDirty = nil
+}
+
+// Remembers changed documents if rebuild is running
+func (service *IndexService) markRebuildDirty(IDs []models.ID) {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	if service.rebuildDirty == nil {
+		return
+	}
+	for _, id := range IDs {
+		service.rebuildDirty[id] = struct{}{}
+	}
+}
+
+func (service *IndexService) takeRebuildDirty() (IDs []models.ID) {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	for id := range service.rebuildDirty {
+		IDs = append(IDs, id)
+	}
+	service.rebuildDirty = map[models.ID]struct{}{}
+	return IDs
+}
+
+func (service *IndexService) setRebuildProgress(indexed int, total int) {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	service.rebuildStatus.IndexedDocuments = indexed
+	service.rebuildStatus.TotalDocuments = total
+}
+
+func (service *IndexService) rebuild(documentRepository DocumentIDLister, path string) error {
+	newPath := path + ".new"
+	if err := os.RemoveAll(newPath); err != nil {
+		return fmt.Errorf("unable to remove leftovers of previous rebuild: %w", err)
+	}
+
+	newIndex, err := NewIndex(newPath)
+	if err != nil {
+		return fmt.Errorf("unable to create new index: %w", err)
+	}
+
+	if err := service.fillIndex(newIndex, documentRepository); err != nil {
+		newIndex.Close()
+		os.RemoveAll(newPath)
+		return err
+	}
+
+	if err := service.swapIndex(newIndex, newPath, documentRepository, path); err != nil {
+		os.RemoveAll(newPath)
+		return err
+	}
+
+	if err := os.RemoveAll(path + ".old"); err != nil {
+		return fmt.Errorf("unable to remove old index: %w", err)
+	}
+
+	return nil
+}
+
+func (service *IndexService) fillIndex(index bleve.Index, documentRepository DocumentIDLister) error {
+	IDs, err := documentRepository.ListIDs()
+	if err != nil {
+		return fmt.Errorf("unable to list database documents ids: %w", err)
+	}
+	service.setRebuildProgress(0, len(IDs))
+
+	for start := 0; start < len(IDs); start += rebuildBatchSize {
+		end := min(start+rebuildBatchSize, len(IDs))
+
+		documents, err := documentRepository.ReadMany(IDs[start:end])
+		if err != nil {
+			return fmt.Errorf("unable to read database documents: %w", err)
+		}
+
+		if err := service.indexDocuments(index, documents); err != nil {
+			return fmt.Errorf("unable to index documents into new index: %w", err)
+		}
+		service.setRebuildProgress(end, len(IDs))
+	}
+
+	return nil
+}
+
+/*
+Replays documents changed during rebuild into new index and replaces current index with it.
+Requests are blocked while indexes are swapped, so no change can be written into old index after replay.
+*/
+func (service *IndexService) swapIndex(newIndex bleve.Index, newPath string, documentRepository DocumentIDLister, path string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if err := service.replayDirty(newIndex, documentRepository); err != nil {
+		newIndex.Close()
+		return err
+	}
+
+	// Index directory can not be moved while index is open, so both indexes are closed and new one is reopened
+	if err := newIndex.Close(); err != nil {
+		return fmt.Errorf("unable to close new index: %w", err)
+	}
+	if err := service.index.Close(); err != nil {
+		return service.reopen(path, fmt.Errorf("unable to close current index: %w", err))
+	}
+
+	oldPath := path + ".old"
+	if err := os.RemoveAll(oldPath); err != nil {
+		return service.reopen(path, fmt.Errorf("unable to remove old index: %w", err))
+	}
+	if err := service.renameDir(path, oldPath); err != nil {
+		return service.reopen(path, fmt.Errorf("unable to move current index: %w", err))
+	}
+	if err := service.renameDir(newPath, path); err != nil {
+		err = fmt.Errorf("unable to move new index: %w", err)
+		if restoreErr := service.renameDir(oldPath, path); restoreErr != nil {
+			// Current index is served from old path until restart, new index is removed after failed rebuild
+			return service.reopen(oldPath, errors.Join(err, fmt.Errorf("unable to move current index back: %w", restoreErr)))
+		}
+		return service.reopen(path, err)
+	}
+
+	return service.reopen(path, nil)
+}
+
+func (service *IndexService) replayDirty(index bleve.Index, documentRepository DocumentIDLister) error {
+	IDs := service.takeRebuildDirty()
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	documents, err := documentRepository.ReadMany(IDs)
+	if err != nil {
+		return fmt.Errorf("unable to read changed documents: %w", err)
+	}
+
+	found := make(map[models.ID]struct{}, len(documents))
+	for _, document := range documents {
+		found[document.ID] = struct{}{}
+	}
+	deletedIDs := []models.ID{}
+	for _, id := range IDs {
+		if _, ok := found[id]; !ok {
+			deletedIDs = append(deletedIDs, id)
+		}
+	}
+
+	if err := service.indexDocuments(index, documents); err != nil {
+		return fmt.Errorf("unable to index changed documents into new index: %w", err)
+	}
+	if err := deleteDocuments(index, deletedIDs); err != nil {
+		return fmt.Errorf("unable to delete changed documents from new index: %w", err)
+	}
+
+	return nil
+}
+
+/*
+Opens index at path as current one, passed cause is returned after index is opened.
+If index can not be opened, current index stays closed and service is reported unavailable in RebuildStatus.
+*/
+func (service *IndexService) reopen(path string, cause error) error {
+	index, err := bleve.Open(path)
+	service.setIndexUnavailable(err != nil)
+	if err != nil {
+		err = errors.Join(cause, fmt.Errorf("unable to open index: %w", err))
+		log.Printf("index at '%s' is closed and can not be reopened, searches will fail until index is rebuilt or service is restarted: %s", path, err)
+		return err
+	}
+	service.index = index
+	return cause
+}
+
+func (service *IndexService) setIndexUnavailable(unavailable bool) {
+	service.rebuildMutex.Lock()
+	defer service.rebuildMutex.Unlock()
+
+	service.rebuildStatus.IndexUnavailable = unavailable
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SwapIndex_Rename_Failure(t *testing.T) {
+	for _, failRestore := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "index.bleve")
+		newPath := path + ".new"
+
+		currentIndex, err := NewIndex(path)
+		require.NoError(t, err)
+		require.NoError(t, currentIndex.Index("1", map[string]interface{}{"name": "current"}))
+		newIndex, err := NewIndex(newPath)
+		require.NoError(t, err)
+		require.NoError(t, newIndex.Index("2", map[string]interface{}{"name": "new"}))
+
+		service := NewIndexService(currentIndex, nil, nil)
+		service.renameDir = func(from string, to string) error {
+			if from == newPath || failRestore && from == path+".old" {
+				return errors.New("rename failed")
+			}
+			return os.Rename(from, to)
+		}
+		require.Error(t, service.swapIndex(newIndex, newPath, nil, path))
+
+		// Current index must be reopened and keep serving requests
+		document, err := service.index.Document("1")
+		require.NoError(t, err, "restore fails: %t", failRestore)
+		require.NotNil(t, document, "restore fails: %t", failRestore)
+		require.False(t, service.RebuildStatus().IndexUnavailable)
+		require.NoError(t, service.index.Close())
+	}
+}
+
+func Test_SwapIndex_Reopen_Failure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.bleve")
+	newPath := path + ".new"
+
+	currentIndex, err := NewIndex(path)
+	require.NoError(t, err)
+	newIndex, err := NewIndex(newPath)
+	require.NoError(t, err)
+
+	service := NewIndexService(currentIndex, nil, nil)
+	// Current index is moved away and lost, so there is nothing to reopen
+	service.renameDir = func(from string, to string) error {
+		if from == newPath {
+			return errors.New("rename failed")
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		return os.RemoveAll(to)
+	}
+	require.Error(t, service.swapIndex(newIndex, newPath, nil, path))
+	require.True(t, service.RebuildStatus().IndexUnavailable)
+}
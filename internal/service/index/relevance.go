@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/numeric"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+// Profile used by search requests without profile if it is configured
+const DefaultRelevanceProfileName = "default"
+
+var (
+	ErrUnknownRelevanceProfile = errors.New("unknown relevance profile")
+	ErrInvalidRelevanceProfile = errors.New("invalid relevance profile")
+)
+
+/*
+Named set of ranking adjustments applied to scores of documents found by query.
+Without profile query matches in every field count the same and document age is not taken into account.
+*/
+type RelevanceProfile struct {
+	// Boosts of query matches in `name` and `body`, field without boost has boost 1
+	FieldBoosts map[string]float64 `json:"fieldBoosts"`
+	// Boosts of documents having tag, added to score of document matched by query
+	TagBoosts map[TagName]float64 `json:"tagBoosts"`
+	// Decay of score of old documents, age is not taken into account if nil
+	Recency *RecencyDecay `json:"recency"`
+}
+
+type RecencyDecay struct {
+	// Age of document after which decaying part of its score is halved, e.g. `720h`
+	HalfLife time.Duration `json:"halfLife"`
+	// Part of score which decays, between 0 and 1. Score of very old document is multiplied by 1 - Weight
+	Weight float64 `json:"weight"`
+}
+
+// Half life is written as duration string instead of nanoseconds
+func (decay *RecencyDecay) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		HalfLife string  `json:"halfLife"`
+		Weight   float64 `json:"weight"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	halfLife, err := time.ParseDuration(raw.HalfLife)
+	if err != nil {
+		return fmt.Errorf("%w: unable to parse half life: %w", ErrInvalidRelevanceProfile, err)
+	}
+	decay.HalfLife, decay.Weight = halfLife, raw.Weight
+	return nil
+}
+
+func (profile *RelevanceProfile) validate() error {
+	for field, boost := range profile.FieldBoosts {
+		if !slices.Contains(analyzedFields, field) {
+			return fmt.Errorf("%w: unknown field '%s', expected one of %v", ErrInvalidRelevanceProfile, field, analyzedFields)
+		}
+		if boost <= 0 {
+			return fmt.Errorf("%w: boost of field '%s' must be positive", ErrInvalidRelevanceProfile, field)
+		}
+	}
+
+	for tag, boost := range profile.TagBoosts {
+		if boost <= 0 {
+			return fmt.Errorf("%w: boost of tag '%s' must be positive", ErrInvalidRelevanceProfile, tag)
+		}
+	}
+
+	if profile.Recency != nil {
+		if profile.Recency.HalfLife <= 0 {
+			return fmt.Errorf("%w: recency half life must be positive", ErrInvalidRelevanceProfile)
+		}
+		if profile.Recency.Weight < 0 || profile.Recency.Weight > 1 {
+			return fmt.Errorf("%w: recency weight must be between 0 and 1", ErrInvalidRelevanceProfile)
+		}
+	}
+
+	return nil
+}
+
+// Reads JSON object of relevance profiles by their names from file
+func LoadRelevanceProfiles(path string) (map[string]RelevanceProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]RelevanceProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("unable to parse relevance profiles: %w", err)
+	}
+
+	for name, profile := range profiles {
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("profile '%s': %w", name, err)
+		}
+	}
+
+	return profiles, nil
+}
+
+// Returns profile by name, default profile if name is empty. Nil is returned if name is empty and default profile is not configured
+func (service *IndexService) relevanceProfile(name string) (*RelevanceProfile, error) {
+	if name == "" {
+		name = DefaultRelevanceProfileName
+		if _, ok := service.RelevanceProfiles[name]; !ok {
+			return nil, nil
+		}
+	}
+
+	profile, ok := service.RelevanceProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownRelevanceProfile, name)
+	}
+	return &profile, nil
+}
+
+/*
+Returns query with scores adjusted by profile. Field boosts are set on queries of language fields
+of match query, which must be built for this request only. Documents with boosted tags are matched
+by optional queries adding their boosts to score, and recency decay multiplies resulting score.
+*/
+func (profile *RelevanceProfile) apply(q query.Query, matchQuery query.Query, now time.Time) query.Query {
+	if matchQuery != nil {
+		boostFields(matchQuery, profile.FieldBoosts)
+	}
+
+	if len(profile.TagBoosts) > 0 {
+		boostedQuery := bleve.NewBooleanQuery()
+		boostedQuery.AddMust(q)
+		for tag, boost := range profile.TagBoosts {
+			tagQuery := bleve.NewTermQuery(tag)
+			tagQuery.SetField(tagsField)
+			tagQuery.SetBoost(boost)
+			boostedQuery.AddShould(tagQuery)
+		}
+		q = boostedQuery
+	}
+
+	if profile.Recency != nil {
+		q = &recencyQuery{query: q, decay: *profile.Recency, now: now}
+	}
+
+	return q
+}
+
+// Multiplies boosts of queries searching language fields of name and body by boosts of these fields
+func boostFields(q query.Query, fieldBoosts map[string]float64) {
+	switch q := q.(type) {
+	case *query.BooleanQuery:
+		for _, clause := range []query.Query{q.Must, q.Should, q.MustNot} {
+			if clause != nil {
+				boostFields(clause, fieldBoosts)
+			}
+		}
+	case *query.ConjunctionQuery:
+		for _, conjunct := range q.Conjuncts {
+			boostFields(conjunct, fieldBoosts)
+		}
+	case *query.DisjunctionQuery:
+		for _, disjunct := range q.Disjuncts {
+			boostFields(disjunct, fieldBoosts)
+		}
+	case query.FieldableQuery:
+		boostableQuery, ok := q.(query.BoostableQuery)
+		if !ok {
+			return
+		}
+		for _, field := range analyzedFields {
+			boost, ok := fieldBoosts[field]
+			if !ok {
+				continue
+			}
+			for _, language := range models.Languages {
+				if q.Field() == analyzedField(field, language) {
+					boostableQuery.SetBoost(boostableQuery.Boost() * boost)
+				}
+			}
+		}
+	}
+}
+
+// Query whose matches have scores decayed by age of documents
+type recencyQuery struct {
+	query query.Query
+	decay RecencyDecay
+	// Age of documents is counted up to this time
+	now time.Time
+}
+
+func (q *recencyQuery) Searcher(ctx context.Context, reader index.IndexReader, indexMapping mapping.IndexMapping, options search.SearcherOptions) (search.Searcher, error) {
+	querySearcher, err := q.query.Searcher(ctx, reader, indexMapping, options)
+	if err != nil {
+		return nil, err
+	}
+
+	docValueReader, err := reader.DocValueReader([]string{createdAtField})
+	if err != nil {
+		querySearcher.Close()
+		return nil, err
+	}
+
+	return &recencySearcher{Searcher: querySearcher, docValueReader: docValueReader, query: q}, nil
+}
+
+// Multiplies scores of matches found by embedded searcher by decay of their creation date
+type recencySearcher struct {
+	search.Searcher
+	docValueReader index.DocValueReader
+	query          *recencyQuery
+}
+
+func (s *recencySearcher) Next(ctx *search.SearchContext) (*search.DocumentMatch, error) {
+	match, err := s.Searcher.Next(ctx)
+	if err != nil || match == nil {
+		return match, err
+	}
+	return match, s.decayScore(match)
+}
+
+func (s *recencySearcher) Advance(ctx *search.SearchContext, ID index.IndexInternalID) (*search.DocumentMatch, error) {
+	match, err := s.Searcher.Advance(ctx, ID)
+	if err != nil || match == nil {
+		return match, err
+	}
+	return match, s.decayScore(match)
+}
+
+func (s *recencySearcher) decayScore(match *search.DocumentMatch) error {
+	var createdAt time.Time
+	err := s.docValueReader.VisitDocValues(match.IndexInternalID, func(field string, term []byte) {
+		// Date is indexed as several prefix coded terms of different precision, only unshifted one is exact
+		if shift, err := numeric.PrefixCoded(term).Shift(); err != nil || shift != 0 {
+			return
+		}
+		if nanoseconds, err := numeric.PrefixCoded(term).Int64(); err == nil {
+			createdAt = time.Unix(0, nanoseconds)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Documents without creation date and documents from future are not decayed
+	age := s.query.now.Sub(createdAt)
+	if createdAt.IsZero() || age < 0 {
+		age = 0
+	}
+	decay := math.Exp2(-float64(age) / float64(s.query.decay.HalfLife))
+	match.Score *= 1 - s.query.decay.Weight + s.query.decay.Weight*decay
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+var (
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// Sort keys accepted in search request mapped to index fields
+var sortKeyFields = map[string]string{
+	"score":   "_score",
+	"id":      idField,
+	"name":    nameSortField,
+	"tags":    tagsCountField,
+	"created": createdAtField,
+	"updated": updatedAtField,
+}
+
+/*
+Converts sort keys from search request into bleve sort order.
+Each key may be prefixed with `-` for descending order, e.g. `[]string{"-tags", "name"}`.
+Document id is appended as last key so results order is stable between requests and pages may be continued with cursor.
+Without keys results are sorted by score, most relevant documents first.
+*/
+func getSortOrder(keys []string) (search.SortOrder, []string, error) {
+	if len(keys) == 0 {
+		order := []string{"-_score", idField}
+		return search.ParseSortOrderStrings(order), order, nil
+	}
+
+	order := make([]string, 0, len(keys)+1)
+	seenFields := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		descending := strings.HasPrefix(key, "-")
+		field, ok := sortKeyFields[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown sort key '%s'", ErrInvalidSort, key)
+		}
+		if _, ok := seenFields[field]; ok {
+			return nil, nil, fmt.Errorf("%w: duplicate sort key '%s'", ErrInvalidSort, key)
+		}
+		seenFields[field] = struct{}{}
+
+		if descending {
+			field = "-" + field
+		}
+		order = append(order, field)
+	}
+
+	if _, ok := seenFields[idField]; !ok {
+		order = append(order, idField)
+	}
+
+	return search.ParseSortOrderStrings(order), order, nil
+}
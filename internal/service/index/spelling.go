@@ -0,0 +1,185 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+const (
+	// Spelling correction is suggested for queries which find fewer documents
+	DefaultSpellingThreshold = 3
+
+	// Words shorter than this are not corrected because almost any term is close to them
+	minCorrectedWordLength = 4
+	// Words up to this length are corrected with one edit, longer ones with two
+	maxSingleEditWordLength = 5
+)
+
+// Fields whose terms are used as dictionary of correctly spelled words
+var spellingFields = []string{nameField, bodyField}
+
+/*
+Returns query where every word which is absent in index dictionary is replaced by dictionary term
+with the least edit distance, the most frequent one among equally distant terms.
+Query syntax is kept: field names, fuzzy, wildcard and regexp terms are not corrected.
+Empty string is returned if no word is corrected.
+*/
+func (service *IndexService) correctSpelling(queryString string) (string, error) {
+	advancedIndex, err := service.index.Advanced()
+	if err != nil {
+		return "", err
+	}
+	reader, err := advancedIndex.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	runes := []rune(queryString)
+	var corrected strings.Builder
+	isCorrected := false
+	inRegexp := false
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			if runes[start] == '/' {
+				inRegexp = !inRegexp
+			}
+			corrected.WriteRune(runes[start])
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+
+		word := string(runes[start:end])
+		if !inRegexp && isCorrectableWord(runes, start, end) {
+			correction, err := correctWord(reader, strings.ToLower(word))
+			if err != nil {
+				return "", err
+			}
+			if correction != "" {
+				word = correction
+				isCorrected = true
+			}
+		}
+		corrected.WriteString(word)
+		start = end
+	}
+
+	if !isCorrected {
+		return "", nil
+	}
+	return corrected.String(), nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Checks that word of query between start and end runes is long enough, has no digits and is not a part of query syntax
+func isCorrectableWord(runes []rune, start int, end int) bool {
+	if end-start < minCorrectedWordLength {
+		return false
+	}
+	for _, r := range runes[start:end] {
+		if unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if start > 0 && strings.ContainsRune(`*?\`, runes[start-1]) {
+		return false
+	}
+	if end < len(runes) && strings.ContainsRune(`:*?~`, runes[end]) {
+		return false
+	}
+	return true
+}
+
+// Returns the best dictionary term for lowercased word or empty string if word is in dictionary or has no close terms
+func correctWord(reader index.IndexReader, word string) (string, error) {
+	wordRunes := []rune(word)
+	maxDistance := 1
+	if len(wordRunes) > maxSingleEditWordLength {
+		maxDistance = 2
+	}
+
+	candidates := map[string]uint64{}
+	for _, field := range spellingFields {
+		if err := collectCloseTerms(reader, field, word, maxDistance, candidates); err != nil {
+			return "", err
+		}
+	}
+	if _, ok := candidates[word]; ok {
+		return "", nil
+	}
+
+	best, bestDistance, bestCount := "", 0, uint64(0)
+	for term, count := range candidates {
+		distance := editDistance(wordRunes, []rune(term))
+		if best == "" || distance < bestDistance ||
+			distance == bestDistance && (count > bestCount || count == bestCount && term < best) {
+			best, bestDistance, bestCount = term, distance, count
+		}
+	}
+	return best, nil
+}
+
+/*
+Adds terms of field within maxDistance edits from word to candidates with their document count.
+Readers which support levenshtein automaton iterate only close terms, others iterate whole field dictionary.
+*/
+func collectCloseTerms(reader index.IndexReader, field string, word string, maxDistance int, candidates map[string]uint64) (err error) {
+	var dictionary index.FieldDict
+	if fuzzyReader, ok := reader.(index.IndexReaderFuzzy); ok {
+		dictionary, err = fuzzyReader.FieldDictFuzzy(field, word, maxDistance, "")
+	} else {
+		dictionary, err = reader.FieldDict(field)
+	}
+	if err != nil {
+		return err
+	}
+	defer dictionary.Close()
+
+	wordRunes := []rune(word)
+	for {
+		entry, err := dictionary.Next()
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return nil
+		}
+		// Readers without automaton return whole dictionary
+		if editDistance(wordRunes, []rune(entry.Term)) <= maxDistance {
+			candidates[entry.Term] += entry.Count
+		}
+	}
+}
+
+// Returns levenshtein distance between words counted in runes
+func editDistance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	DefaultSuggestSize = 10
+	MaxSuggestSize     = 50
+)
+
+var (
+	ErrInvalidSuggestRequest = errors.New("invalid suggest request")
+)
+
+// Separates parts of tag suggestion term
+const tagSuggestSeparator = "\x00"
+
+type SuggestRequest struct {
+	// Text typed by user, every its word is matched as prefix of document name word
+	Prefix string `form:"prefix"`
+	// Maximum quantity of suggested documents and of suggested tags
+	Size int `form:"size"`
+}
+
+type DocumentSuggestion struct {
+	ID   models.ID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type TagSuggestion struct {
+	ID            models.ID     `json:"id"`
+	Name          string        `json:"name"`
+	DocumentCount DocumentCount `json:"documentCount"`
+}
+
+type SuggestResponse struct {
+	// Documents ordered by relevance of name to prefix
+	Documents []DocumentSuggestion `json:"documents"`
+	// Tags whose name starts with prefix ordered by count of documents they are assigned to
+	Tags []TagSuggestion `json:"tags"`
+}
+
+// Fills zero size with default and checks that prefix is not empty and size is within bounds
+func (request *SuggestRequest) validate() error {
+	// Trailing space is kept because it means that last word is typed completely
+	request.Prefix = strings.TrimLeftFunc(request.Prefix, unicode.IsSpace)
+	if request.Prefix == "" {
+		return fmt.Errorf("%w: prefix must not be empty", ErrInvalidSuggestRequest)
+	}
+
+	if request.Size == 0 {
+		request.Size = DefaultSuggestSize
+	}
+	if request.Size < 0 || request.Size > MaxSuggestSize {
+		return fmt.Errorf("%w: size must be between 1 and %d", ErrInvalidSuggestRequest, MaxSuggestSize)
+	}
+
+	return nil
+}
+
+/*
+Returns names of documents and tags starting with typed prefix.
+Suggestions are built from index only, so they are cheap enough to be requested on every keystroke.
+*/
+func (service *IndexService) Suggest(request *SuggestRequest) (response SuggestResponse, err error) {
+	if err := request.validate(); err != nil {
+		return response, err
+	}
+
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	if response.Documents, err = service.suggestDocuments(request); err != nil {
+		return response, err
+	}
+
+	if response.Tags, err = service.suggestTags(request); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Finds documents whose name contains words starting with every word of prefix
+func (service *IndexService) suggestDocuments(request *SuggestRequest) ([]DocumentSuggestion, error) {
+	prefixQuery := bleve.NewMatchQuery(request.Prefix)
+	prefixQuery.SetField(nameSuggestField)
+	prefixQuery.Analyzer = suggestQueryAnalyzerName
+	prefixQuery.SetOperator(query.MatchQueryOperatorAnd)
+
+	searchRequest := bleve.NewSearchRequestOptions(prefixQuery, request.Size, 0, false)
+	searchRequest.SortBy([]string{"-_score", nameSortField})
+	searchRequest.Fields = []string{nameField}
+
+	results, err := service.index.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]DocumentSuggestion, 0, len(results.Hits))
+	for _, hit := range results.Hits {
+		id, err := strconv.ParseInt(hit.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse document id '%s': %w", hit.ID, err)
+		}
+		name, _ := hit.Fields[nameField].(string)
+		documents = append(documents, DocumentSuggestion{ID: id, Name: name})
+	}
+
+	return documents, nil
+}
+
+/*
+Finds tags whose lowercased name starts with lowercased prefix. Tags and their document counts
+are taken from index dictionary, so neither documents nor database are searched.
+*/
+func (service *IndexService) suggestTags(request *SuggestRequest) ([]TagSuggestion, error) {
+	dictionary, err := service.index.FieldDictPrefix(tagsSuggestField, []byte(strings.ToLower(request.Prefix)))
+	if err != nil {
+		return nil, err
+	}
+	defer dictionary.Close()
+
+	suggestions := map[models.ID]TagSuggestion{}
+	for {
+		entry, err := dictionary.Next()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tags dictionary: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+
+		suggestion, err := parseTagSuggestTerm(entry.Term)
+		if err != nil {
+			return nil, err
+		}
+		suggestion.DocumentCount = DocumentCount(entry.Count)
+
+		// Documents which are not reindexed yet after tag rename have old name of tag, the most common one is suggested
+		if previous, ok := suggestions[suggestion.ID]; ok {
+			if previous.DocumentCount > suggestion.DocumentCount {
+				suggestion.Name = previous.Name
+			}
+			suggestion.DocumentCount += previous.DocumentCount
+		}
+		suggestions[suggestion.ID] = suggestion
+	}
+
+	tags := make([]TagSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		tags = append(tags, suggestion)
+	}
+
+	slices.SortFunc(tags, func(a, b TagSuggestion) int {
+		if order := cmp.Compare(b.DocumentCount, a.DocumentCount); order != 0 {
+			return order
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return tags[:min(len(tags), request.Size)], nil
+}
+
+/*
+Returns terms of tags suggestion field. Term starts with lowercased tag name to be found by typed prefix
+and keeps tag id and original name, so suggestions are built without reading tags from database.
+*/
+func tagSuggestTerms(tags []models.TagResponse) []string {
+	terms := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		terms = append(terms, strings.Join([]string{strings.ToLower(tag.Name), strconv.FormatInt(tag.ID, 10), tag.Name}, tagSuggestSeparator))
+	}
+	return terms
+}
+
+func parseTagSuggestTerm(term string) (suggestion TagSuggestion, err error) {
+	parts := strings.Split(term, tagSuggestSeparator)
+	if len(parts) != 3 {
+		return suggestion, fmt.Errorf("malformed tag suggestion term '%s'", term)
+	}
+	if suggestion.ID, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return suggestion, fmt.Errorf("unable to parse tag id of suggestion term '%s': %w", term, err)
+	}
+	suggestion.Name = parts[2]
+	return suggestion, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+var (
+	ErrInvalidTagFilter = errors.New("invalid tag filter")
+)
+
+const (
+	tagFilterAnd        = '&'
+	tagFilterOr         = '|'
+	tagFilterNot        = '!'
+	tagFilterOpenGroup  = '('
+	tagFilterCloseGroup = ')'
+	tagFilterQuote      = '"'
+	tagFilterEscape     = '\\'
+)
+
+/*
+Tag filter is a boolean expression over tag names, e.g. `(politics | economy) & !sport`.
+
+Operators in order of decreasing precedence:
+  - `!` - document must not have tag (or match subexpression)
+  - `&` - document must match both operands
+  - `|` - document must match at least one of operands
+
+Parentheses group subexpressions. Tag names may contain spaces, names containing
+operator characters must be quoted: `"c&c" | "rock'n'roll"`. Inside quotes `\` escapes next character.
+*/
+type tagFilter struct {
+	root tagFilterNode
+}
+
+type tagFilterNode interface {
+	query(field string) query.Query
+	// Visits every tag in expression, negated is true if tag is under odd number of negations
+	visitTags(negated bool, visitor func(node *tagFilterTagNode, negated bool))
+}
+
+type tagFilterTagNode struct {
+	name string
+}
+
+type tagFilterNotNode struct {
+	operand tagFilterNode
+}
+
+type tagFilterAndNode struct {
+	operands []tagFilterNode
+}
+
+type tagFilterOrNode struct {
+	operands []tagFilterNode
+}
+
+func (node *tagFilterTagNode) query(field string) query.Query {
+	termQuery := bleve.NewTermQuery(node.name)
+	termQuery.SetField(field)
+	return termQuery
+}
+
+func (node *tagFilterTagNode) visitTags(negated bool, visitor func(node *tagFilterTagNode, negated bool)) {
+	visitor(node, negated)
+}
+
+func (node *tagFilterNotNode) query(field string) query.Query {
+	booleanQuery := bleve.NewBooleanQuery()
+	booleanQuery.AddMust(bleve.NewMatchAllQuery())
+	booleanQuery.AddMustNot(node.operand.query(field))
+	return booleanQuery
+}
+
+func (node *tagFilterNotNode) visitTags(negated bool, visitor func(node *tagFilterTagNode, negated bool)) {
+	node.operand.visitTags(!negated, visitor)
+}
+
+// Negated operands are added as MustNot clauses directly instead of nesting match all queries
+func (node *tagFilterAndNode) query(field string) query.Query {
+	booleanQuery := bleve.NewBooleanQuery()
+	hasMust := false
+	for _, operand := range node.operands {
+		if notNode, ok := operand.(*tagFilterNotNode); ok {
+			booleanQuery.AddMustNot(notNode.operand.query(field))
+			continue
+		}
+		booleanQuery.AddMust(operand.query(field))
+		hasMust = true
+	}
+	if !hasMust {
+		booleanQuery.AddMust(bleve.NewMatchAllQuery())
+	}
+	return booleanQuery
+}
+
+func (node *tagFilterAndNode) visitTags(negated bool, visitor func(node *tagFilterTagNode, negated bool)) {
+	for _, operand := range node.operands {
+		operand.visitTags(negated, visitor)
+	}
+}
+
+func (node *tagFilterOrNode) query(field string) query.Query {
+	booleanQuery := bleve.NewBooleanQuery()
+	for _, operand := range node.operands {
+		booleanQuery.AddShould(operand.query(field))
+	}
+	booleanQuery.SetMinShould(1)
+	return booleanQuery
+}
+
+func (node *tagFilterOrNode) visitTags(negated bool, visitor func(node *tagFilterTagNode, negated bool)) {
+	for _, operand := range node.operands {
+		operand.visitTags(negated, visitor)
+	}
+}
+
+// Returns error wrapping ErrInvalidTagFilter if expression has syntax errors
+func parseTagFilter(expression string) (*tagFilter, error) {
+	parser := tagFilterParser{input: []rune(expression)}
+
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	parser.skipSpaces()
+	if !parser.end() {
+		return nil, parser.errorf("unexpected '%c'", parser.peek())
+	}
+
+	return &tagFilter{root: root}, nil
+}
+
+func (filter *tagFilter) query(field string) query.Query {
+	return filter.root.query(field)
+}
+
+// Returns names of tags which documents must have and names of tags which documents must not have
+func (filter *tagFilter) tags() (included []string, excluded []string) {
+	filter.root.visitTags(false, func(node *tagFilterTagNode, negated bool) {
+		if negated {
+			excluded = append(excluded, node.name)
+		} else {
+			included = append(included, node.name)
+		}
+	})
+	return included, excluded
+}
+
+// Replaces tag names in expression, names which are not in replacements are kept
+func (filter *tagFilter) replaceTags(replacements map[string]string) {
+	filter.root.visitTags(false, func(node *tagFilterTagNode, _ bool) {
+		if replacement, ok := replacements[node.name]; ok {
+			node.name = replacement
+		}
+	})
+}
+
+// Recursive descent parser of tag filter expression
+type tagFilterParser struct {
+	input    []rune
+	position int
+}
+
+func (parser *tagFilterParser) parseOr() (tagFilterNode, error) {
+	operand, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []tagFilterNode{operand}
+	for parser.consume(tagFilterOr) {
+		operand, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &tagFilterOrNode{operands: operands}, nil
+}
+
+func (parser *tagFilterParser) parseAnd() (tagFilterNode, error) {
+	operand, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []tagFilterNode{operand}
+	for parser.consume(tagFilterAnd) {
+		operand, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &tagFilterAndNode{operands: operands}, nil
+}
+
+func (parser *tagFilterParser) parseNot() (tagFilterNode, error) {
+	if parser.consume(tagFilterNot) {
+		operand, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		// Double negation is the operand itself
+		if notNode, ok := operand.(*tagFilterNotNode); ok {
+			return notNode.operand, nil
+		}
+		return &tagFilterNotNode{operand: operand}, nil
+	}
+	return parser.parsePrimary()
+}
+
+func (parser *tagFilterParser) parsePrimary() (tagFilterNode, error) {
+	parser.skipSpaces()
+	if parser.end() {
+		return nil, parser.errorf("tag name or '(' expected")
+	}
+
+	switch parser.peek() {
+	case tagFilterOpenGroup:
+		parser.position++
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.consume(tagFilterCloseGroup) {
+			return nil, parser.errorf("')' expected")
+		}
+		return node, nil
+	case tagFilterQuote:
+		return parser.parseQuotedTag()
+	default:
+		return parser.parseTag()
+	}
+}
+
+func (parser *tagFilterParser) parseQuotedTag() (tagFilterNode, error) {
+	start := parser.position
+	parser.position++ // opening quote
+
+	var name strings.Builder
+	for !parser.end() {
+		char := parser.peek()
+		parser.position++
+		switch char {
+		case tagFilterQuote:
+			if name.Len() == 0 {
+				return nil, parser.errorf("empty tag name")
+			}
+			return &tagFilterTagNode{name: name.String()}, nil
+		case tagFilterEscape:
+			if parser.end() {
+				return nil, parser.errorf("unfinished escape sequence")
+			}
+			name.WriteRune(parser.peek())
+			parser.position++
+		default:
+			name.WriteRune(char)
+		}
+	}
+
+	parser.position = start
+	return nil, parser.errorf("unterminated quote")
+}
+
+func (parser *tagFilterParser) parseTag() (tagFilterNode, error) {
+	start := parser.position
+	for !parser.end() && !isTagFilterOperator(parser.peek()) {
+		parser.position++
+	}
+
+	name := strings.TrimSpace(string(parser.input[start:parser.position]))
+	if name == "" {
+		parser.position = start
+		return nil, parser.errorf("unexpected '%c'", parser.peek())
+	}
+	return &tagFilterTagNode{name: name}, nil
+}
+
+// Skips spaces and consumes expected character if it is next
+func (parser *tagFilterParser) consume(expected rune) bool {
+	parser.skipSpaces()
+	if !parser.end() && parser.peek() == expected {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *tagFilterParser) skipSpaces() {
+	for !parser.end() && unicode.IsSpace(parser.peek()) {
+		parser.position++
+	}
+}
+
+func (parser *tagFilterParser) peek() rune {
+	return parser.input[parser.position]
+}
+
+func (parser *tagFilterParser) end() bool {
+	return parser.position >= len(parser.input)
+}
+
+func (parser *tagFilterParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidTagFilter, fmt.Sprintf(format, args...), parser.position)
+}
+
+func isTagFilterOperator(char rune) bool {
+	switch char {
+	case tagFilterAnd, tagFilterOr, tagFilterNot, tagFilterOpenGroup, tagFilterCloseGroup, tagFilterQuote:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+)
+
+// Number of documents compared and repaired at once during verification
+const verifyBatchSize = 1000
+
+type DocumentIDLister interface {
+	ListIDs() (response []models.ID, err error)
+	ReadMany(IDs []models.ID) (response []models.DocumentResponse, err error)
+}
+
+// Document which exists both in database and index but has different fields
+type StaleDocument struct {
+	ID     models.ID `json:"id"`
+	Fields []string  `json:"fields"`
+}
+
+type VerifyReport struct {
+	DatabaseDocuments int             `json:"databaseDocuments"`
+	IndexDocuments    int             `json:"indexDocuments"`
+	Missing           []models.ID     `json:"missing"`  // documents which are in database but not in index
+	Orphaned          []models.ID     `json:"orphaned"` // documents which are in index but not in database
+	Stale             []StaleDocument `json:"stale"`
+	Repaired          bool            `json:"repaired"`
+}
+
+func (report *VerifyReport) Consistent() bool {
+	return len(report.Missing) == 0 && len(report.Orphaned) == 0 && len(report.Stale) == 0
+}
+
+/*
+Compares every document in database with its index document field by field and finds index documents
+without database rows. If repair is true, missing and stale documents are reindexed and orphaned ones are deleted.
+
+Changes which are still waiting in index outbox are reported too, so report is exact only when outbox is empty.
+*/
+func (service *IndexService) Verify(documentRepository DocumentIDLister, repair bool) (report VerifyReport, err error) {
+	report = VerifyReport{
+		Missing:  []models.ID{},
+		Orphaned: []models.ID{},
+		Stale:    []StaleDocument{},
+		Repaired: repair,
+	}
+
+	databaseIDs, err := documentRepository.ListIDs()
+	if err != nil {
+		return report, fmt.Errorf("unable to list database documents ids: %w", err)
+	}
+	report.DatabaseDocuments = len(databaseIDs)
+
+	indexIDs, err := service.indexedIDs()
+	if err != nil {
+		return report, fmt.Errorf("unable to list index documents ids: %w", err)
+	}
+	report.IndexDocuments = len(indexIDs)
+
+	for start := 0; start < len(databaseIDs); start += verifyBatchSize {
+		batchIDs := databaseIDs[start:min(start+verifyBatchSize, len(databaseIDs))]
+
+		documents, err := documentRepository.ReadMany(batchIDs)
+		if err != nil {
+			return report, fmt.Errorf("unable to read database documents: %w", err)
+		}
+
+		indexed, err := service.indexedDocuments(batchIDs)
+		if err != nil {
+			return report, fmt.Errorf("unable to read index documents: %w", err)
+		}
+
+		toIndex := []models.DocumentResponse{}
+		for _, document := range documents {
+			indexDocument, ok := indexed[document.ID]
+			if !ok {
+				report.Missing = append(report.Missing, document.ID)
+				toIndex = append(toIndex, document)
+				continue
+			}
+			if fields := differentFields(document, indexDocument); len(fields) > 0 {
+				report.Stale = append(report.Stale, StaleDocument{ID: document.ID, Fields: fields})
+				toIndex = append(toIndex, document)
+			}
+		}
+
+		if repair && len(toIndex) > 0 {
+			if err := service.Index(toIndex); err != nil {
+				return report, fmt.Errorf("unable to repair index documents: %w", err)
+			}
+		}
+	}
+
+	for _, id := range indexIDs {
+		if _, found := slices.BinarySearch(databaseIDs, id); !found {
+			report.Orphaned = append(report.Orphaned, id)
+		}
+	}
+
+	if repair {
+		for start := 0; start < len(report.Orphaned); start += verifyBatchSize {
+			if err := service.Delete(report.Orphaned[start:min(start+verifyBatchSize, len(report.Orphaned))]); err != nil {
+				return report, fmt.Errorf("unable to delete orphaned index documents: %w", err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Returns ids of all documents in index in ascending order
+func (service *IndexService) indexedIDs() (IDs []models.ID, err error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	searchRequest := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), verifyBatchSize, 0, false)
+	searchRequest.SortBy([]string{"_id"})
+
+	for {
+		searchResult, err := service.index.Search(searchRequest)
+		if err != nil {
+			return IDs, err
+		}
+
+		for _, hit := range searchResult.Hits {
+			id, err := strconv.Atoi(hit.ID)
+			if err != nil {
+				return IDs, fmt.Errorf("unable to convert index document id '%s' into int: %w", hit.ID, err)
+			}
+			IDs = append(IDs, int64(id))
+		}
+
+		if len(searchResult.Hits) < verifyBatchSize {
+			// Documents are paged in order of string ids
+			slices.Sort(IDs)
+			return IDs, nil
+		}
+		searchRequest.SetSearchAfter(searchResult.Hits[len(searchResult.Hits)-1].Sort)
+	}
+}
+
+// Returns stored fields of index documents with passed ids
+func (service *IndexService) indexedDocuments(IDs []models.ID) (documents map[models.ID]IndexDocument, err error) {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+
+	documentIDs := make([]string, 0, len(IDs))
+	for _, id := range IDs {
+		documentIDs = append(documentIDs, fmt.Sprint(id))
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(bleve.NewDocIDQuery(documentIDs), len(documentIDs), 0, false)
+	searchRequest.Fields = []string{nameField, bodyField, tagsField}
+
+	searchResult, err := service.index.Search(searchRequest)
+	if err != nil {
+		return documents, err
+	}
+
+	documents = make(map[models.ID]IndexDocument, len(searchResult.Hits))
+	for _, hit := range searchResult.Hits {
+		id, err := strconv.Atoi(hit.ID)
+		if err != nil {
+			return documents, fmt.Errorf("unable to convert index document id '%s' into int: %w", hit.ID, err)
+		}
+		name, _ := hit.Fields[nameField].(string)
+		body, _ := hit.Fields[bodyField].(string)
+		documents[int64(id)] = IndexDocument{
+			ID:   int64(id),
+			Name: name,
+			Body: body,
+			Tags: storedStrings(hit.Fields[tagsField]),
+		}
+	}
+
+	return documents, nil
+}
+
+// Stored field with single value is returned by bleve as value itself and with many values as slice
+func storedStrings(field interface{}) (values []string) {
+	switch field := field.(type) {
+	case string:
+		return []string{field}
+	case []interface{}:
+		for _, value := range field {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// Returns names of fields which differ between database document and its index document
+func differentFields(document models.DocumentResponse, indexDocument IndexDocument) (fields []string) {
+	if document.Name != indexDocument.Name {
+		fields = append(fields, nameField)
+	}
+	if document.Body != indexDocument.Body {
+		fields = append(fields, bodyField)
+	}
+
+	databaseTags := document.TagNames()
+	indexTags := slices.Clone(indexDocument.Tags)
+	slices.Sort(databaseTags)
+	slices.Sort(indexTags)
+	if !slices.Equal(databaseTags, indexTags) {
+		fields = append(fields, tagsField)
+	}
+
+	return fields
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"gopkg.in/guregu/null.v4"
+)
+
+const (
+	// Maximum number of outbox entries processed in one index batch
+	DefaultBatchSize = 1000
+	// Outbox is polled with this interval even without notifications to pick up retried entries
+	DefaultPollInterval = time.Second
+	// Delay before first retry of failed entry, doubled on every next attempt
+	DefaultRetryDelay = time.Second
+	// Upper bound for delay between retries
+	MaxRetryDelay = 5 * time.Minute
+	// Entry which failed this many times is marked dead and not retried anymore
+	DefaultMaxAttempts = 20
+)
+
+type Repository interface {
+	Fetch(limit int, now time.Time) (response []models.OutboxEntry, err error)
+	Complete(entries []models.OutboxEntry) (err error)
+	Fail(entries []models.OutboxEntry) (err error)
+}
+
+type DocumentReader interface {
+	ReadMany(IDs []models.ID) (response []models.DocumentResponse, err error)
+}
+
+type Indexer interface {
+	Index(documents []models.DocumentResponse) error
+	Delete(IDs []models.ID) error
+}
+
+/*
+Worker drains index outbox: documents which still exist in database are indexed
+with their current state, documents which do not exist anymore are deleted from index.
+Failed entries are retried with exponential backoff until they fail MaxAttempts times,
+then they are marked dead and are only reported by index verification.
+*/
+type Worker struct {
+	repository         Repository
+	documentRepository DocumentReader
+	indexService       Indexer
+	notifications      chan struct{}
+
+	BatchSize    int
+	PollInterval time.Duration
+	MaxAttempts  int
+}
+
+func NewWorker(repository Repository, documentRepository DocumentReader, indexService Indexer) *Worker {
+	return &Worker{
+		repository:         repository,
+		documentRepository: documentRepository,
+		indexService:       indexService,
+		notifications:      make(chan struct{}, 1),
+		BatchSize:          DefaultBatchSize,
+		PollInterval:       DefaultPollInterval,
+		MaxAttempts:        DefaultMaxAttempts,
+	}
+}
+
+// Wakes worker up after outbox entries were committed. Never blocks.
+func (worker *Worker) Notify() {
+	select {
+	case worker.notifications <- struct{}{}:
+	default:
+	}
+}
+
+// Processes outbox until context is cancelled
+func (worker *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := worker.Drain(); err != nil {
+			log.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-worker.notifications:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Processes batches until there are no available entries left or batch fails
+func (worker *Worker) Drain() error {
+	for {
+		processed, err := worker.processBatch(time.Now())
+		if err != nil {
+			return err
+		}
+		if processed < worker.BatchSize {
+			return nil
+		}
+	}
+}
+
+// Returns number of processed entries
+func (worker *Worker) processBatch(now time.Time) (int, error) {
+	entries, err := worker.repository.Fetch(worker.BatchSize, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	if err := worker.sync(entries); err != nil {
+		for i := range entries {
+			entries[i].Attempts++
+			entries[i].AvailableAt = now.Add(retryDelay(entries[i].Attempts)).UnixMilli()
+			entries[i].LastError = null.StringFrom(err.Error())
+			if entries[i].Attempts >= worker.MaxAttempts {
+				entries[i].Dead = true
+				log.Printf("index outbox entry %d of document %d failed %d times and will not be retried: %s", entries[i].ID, entries[i].DocumentID, entries[i].Attempts, err)
+			}
+		}
+		if failErr := worker.repository.Fail(entries); failErr != nil {
+			log.Println(failErr)
+		}
+		return 0, err
+	}
+
+	return len(entries), worker.repository.Complete(entries)
+}
+
+// Brings index documents of entries in line with database
+func (worker *Worker) sync(entries []models.OutboxEntry) error {
+	IDs := make([]models.ID, 0, len(entries))
+	seen := make(map[models.ID]struct{}, len(entries))
+	for _, entry := range entries {
+		if _, ok := seen[entry.DocumentID]; ok {
+			continue
+		}
+		seen[entry.DocumentID] = struct{}{}
+		IDs = append(IDs, entry.DocumentID)
+	}
+
+	documents, err := worker.documentRepository.ReadMany(IDs)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		delete(seen, document.ID)
+	}
+	deletedIDs := make([]models.ID, 0, len(seen))
+	for _, id := range IDs {
+		if _, ok := seen[id]; ok {
+			deletedIDs = append(deletedIDs, id)
+		}
+	}
+
+	if len(documents) > 0 {
+		if err := worker.indexService.Index(documents); err != nil {
+			return err
+		}
+	}
+
+	if len(deletedIDs) > 0 {
+		if err := worker.indexService.Delete(deletedIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := DefaultRetryDelay
+	for i := 1; i < attempts && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
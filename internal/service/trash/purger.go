@@ -0,0 +1,61 @@
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+)
+
+const (
+	// Items stay in trash for this time before they are deleted permanently
+	DefaultRetention = 30 * 24 * time.Hour
+	// Trash is checked for expired items with this interval
+	DefaultPurgeInterval = time.Hour
+)
+
+type Repository interface {
+	Purge(before time.Time) (response models.PurgeResult, err error)
+}
+
+// Purger permanently deletes items which are in trash longer than retention
+type Purger struct {
+	repository Repository
+
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewPurger(repository Repository, retention time.Duration) *Purger {
+	return &Purger{
+		repository: repository,
+		Retention:  retention,
+		Interval:   DefaultPurgeInterval,
+	}
+}
+
+// Purges expired items on start and then every interval until context is cancelled
+func (purger *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(purger.Interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := purger.Purge(time.Now()); err != nil {
+			log.Println(err)
+		} else if result.Documents > 0 || result.Tags > 0 {
+			log.Printf("purged %d documents and %d tags from trash", result.Documents, result.Tags)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deletes items which were moved to trash before now minus retention
+func (purger *Purger) Purge(now time.Time) (models.PurgeResult, error) {
+	return purger.repository.Purge(now.Add(-purger.Retention))
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Names of supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+/*
+Opens database of passed driver and applies pending migrations.
+Path is used for SQLite database file, DSN is used for PostgreSQL connection.
+*/
+func Connect(driver string, path string, dsn string) *sqlx.DB {
+	db := OpenDriver(driver, path, dsn)
+
+	if _, err := MigrateUp(db); err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// Opens database of passed driver without applying migrations
+func OpenDriver(driver string, path string, dsn string) *sqlx.DB {
+	switch driver {
+	case DriverSQLite:
+		return Open(path)
+	case DriverPostgres:
+		return OpenPostgres(dsn)
+	default:
+		panic(fmt.Errorf("unknown database driver '%s'", driver))
+	}
+}
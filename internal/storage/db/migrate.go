@@ -0,0 +1,295 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations of every driver are kept in directory named after driver
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationsFS embed.FS
+
+// Migration file name format: `<version>_<name>.<up|down>.sql`, e.g. `0002_tag_parent.up.sql`
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrSchemaTooNew    = errors.New("database schema is newer than application supports")
+	ErrMigrationBroken = errors.New("migration files are inconsistent")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// Returns embedded migrations of driver ordered by version
+func Migrations(driver string) (migrations []Migration, err error) {
+	directory := path.Join("migrations", driver)
+	files, err := fs.ReadDir(migrationsFS, directory)
+	if err != nil {
+		return migrations, fmt.Errorf("no migrations for driver '%s': %w", driver, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		match := migrationFileName.FindStringSubmatch(file.Name())
+		if match == nil {
+			return migrations, fmt.Errorf("%w: unexpected file '%s'", ErrMigrationBroken, file.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return migrations, err
+		}
+		content, err := migrationsFS.ReadFile(path.Join(directory, file.Name()))
+		if err != nil {
+			return migrations, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return migrations, fmt.Errorf("%w: version %d has names '%s' and '%s'", ErrMigrationBroken, version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return migrations, fmt.Errorf("%w: migration %d must have both up and down files", ErrMigrationBroken, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return migrations, fmt.Errorf("%w: migration %d is missing", ErrMigrationBroken, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Returns version of last applied migration, 0 if no migrations were applied
+func SchemaVersion(db *sqlx.DB) (version int, err error) {
+	if err := createSchemaVersionTable(db); err != nil {
+		return 0, err
+	}
+	err = db.Get(&version, "SELECT COALESCE(MAX(version), 0) FROM schema_version")
+	return version, err
+}
+
+// Returns all known migrations with time they were applied at
+func Status(db *sqlx.DB) (statuses []MigrationStatus, err error) {
+	migrations, err := Migrations(db.DriverName())
+	if err != nil {
+		return statuses, err
+	}
+
+	if err := createSchemaVersionTable(db); err != nil {
+		return statuses, err
+	}
+
+	var applied []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := db.Select(&applied, "SELECT version, applied_at FROM schema_version"); err != nil {
+		return statuses, err
+	}
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, migration := range applied {
+		appliedAt[migration.Version] = migration.AppliedAt
+	}
+
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+/*
+Applies all pending migrations in single transaction and returns applied ones.
+Returns ErrSchemaTooNew if database was migrated by newer application version.
+*/
+func MigrateUp(db *sqlx.DB) (applied []Migration, err error) {
+	migrations, err := Migrations(db.DriverName())
+	if err != nil {
+		return applied, err
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return applied, err
+	}
+	if version > len(migrations) {
+		return applied, fmt.Errorf("%w: schema version %d, latest known version %d", ErrSchemaTooNew, version, len(migrations))
+	}
+
+	pending := migrations[version:]
+	if len(pending) == 0 {
+		return applied, nil
+	}
+
+	err = migrate(db, func(tx *sqlx.Tx) error {
+		for _, migration := range pending {
+			if _, err := tx.Exec(migration.Up); err != nil {
+				return fmt.Errorf("unable to apply migration %d '%s': %w", migration.Version, migration.Name, err)
+			}
+			if _, err := tx.Exec(tx.Rebind("INSERT INTO schema_version (version, applied_at) VALUES (?, ?)"), migration.Version, time.Now().UTC()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, err
+	}
+
+	return pending, nil
+}
+
+// Reverts up to steps last applied migrations in single transaction and returns reverted ones
+func MigrateDown(db *sqlx.DB, steps int) (reverted []Migration, err error) {
+	migrations, err := Migrations(db.DriverName())
+	if err != nil {
+		return reverted, err
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return reverted, err
+	}
+	if version > len(migrations) {
+		return reverted, fmt.Errorf("%w: schema version %d, latest known version %d", ErrSchemaTooNew, version, len(migrations))
+	}
+
+	for i := version - 1; i >= 0 && len(reverted) < steps; i-- {
+		reverted = append(reverted, migrations[i])
+	}
+	if len(reverted) == 0 {
+		return reverted, nil
+	}
+
+	err = migrate(db, func(tx *sqlx.Tx) error {
+		for _, migration := range reverted {
+			if _, err := tx.Exec(migration.Down); err != nil {
+				return fmt.Errorf("unable to revert migration %d '%s': %w", migration.Version, migration.Name, err)
+			}
+			if _, err := tx.Exec(tx.Rebind("DELETE FROM schema_version WHERE version = ?"), migration.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reverted, nil
+}
+
+// Runs migration function in transaction
+func migrate(db *sqlx.DB, migrationFunc func(tx *sqlx.Tx) error) (err error) {
+	if db.DriverName() == DriverSQLite {
+		return migrateSQLite(db, migrationFunc)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migrationFunc(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+SQLite can not alter most of table constraints, so tables with foreign keys pointing to them may need to be recreated.
+Migration function is run with foreign keys disabled and foreign keys are checked before commit.
+Foreign keys pragma has no effect inside transaction, so it is changed on dedicated connection before transaction starts.
+*/
+func migrateSQLite(db *sqlx.DB, migrationFunc func(tx *sqlx.Tx) error) (err error) {
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migrationFunc(tx); err != nil {
+		return err
+	}
+
+	var violations []struct {
+		Table  string `db:"table"`
+		RowID  int64  `db:"rowid"`
+		Parent string `db:"parent"`
+		FKID   int64  `db:"fkid"`
+	}
+	if err := tx.Select(&violations, "PRAGMA foreign_key_check"); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("migration violates foreign key of table '%s' referencing '%s'", violations[0].Table, violations[0].Parent)
+	}
+
+	return tx.Commit()
+}
+
+func createSchemaVersionTable(db *sqlx.DB) (err error) {
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)
+	`)
+	return err
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Migrate_Up_And_Down(t *testing.T) {
+	db := NewDb(":memory:")
+	defer db.Close()
+
+	migrations, err := Migrations(DriverSQLite)
+	require.NoError(t, err)
+
+	version, err := SchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, len(migrations), version, "all migrations must be applied on open")
+
+	applied, err := MigrateUp(db)
+	require.NoError(t, err)
+	require.Empty(t, applied, "applied migrations must not be applied twice")
+
+	_, err = db.Exec("INSERT INTO tags (name, assigned, parent_id) VALUES ('parent', false, NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO tags (name, assigned, parent_id) VALUES ('child', false, 1)")
+	require.NoError(t, err)
+
+	reverted, err := MigrateDown(db, len(migrations)-1)
+	require.NoError(t, err)
+	require.Len(t, reverted, len(migrations)-1)
+	require.Equal(t, len(migrations), reverted[0].Version, "migrations must be reverted from the last one")
+
+	version, err = SchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	var tagsCount int
+	require.NoError(t, db.Get(&tagsCount, "SELECT COUNT(*) FROM tags"))
+	require.Equal(t, 2, tagsCount, "data must survive reverting of parent column")
+
+	applied, err = MigrateUp(db)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrations)-1)
+
+	statuses, err := Status(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+	for _, status := range statuses {
+		require.NotNil(t, status.AppliedAt, "migration %d must be applied", status.Version)
+	}
+}
+
+func Test_Migrate_Newer_Schema(t *testing.T) {
+	db := NewDb(":memory:")
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO schema_version (version, applied_at) VALUES (1000, CURRENT_TIMESTAMP)")
+	require.NoError(t, err)
+
+	_, err = MigrateUp(db)
+	require.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func Test_Migrate_Down_Soft_Delete_With_Trash(t *testing.T) {
+	db := NewDb(":memory:")
+	defer db.Close()
+
+	statements := []string{
+		"INSERT INTO tags (id, name, assigned, parent_id, deleted_at) VALUES (1, 'trashed', false, NULL, CURRENT_TIMESTAMP)",
+		"INSERT INTO tags (id, name, assigned, parent_id) VALUES (2, 'child of trashed', true, 1)",
+		"INSERT INTO tag_aliases (tag, name) VALUES (1, 'alias of trashed')",
+		"INSERT INTO documents (id, name, body, created_at, updated_at, deleted_at) VALUES (1, 'trashed', 'body', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+		"INSERT INTO documents (id, name, body, created_at, updated_at) VALUES (2, 'live', 'body', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+		"INSERT INTO tags_documents (tag, document) VALUES (2, 1)",
+		"INSERT INTO tags_documents (tag, document) VALUES (1, 2)",
+		"INSERT INTO tags_documents (tag, document) VALUES (2, 2)",
+		"INSERT INTO document_revisions (document, revision, name, body, tags, created_at) VALUES (1, 1, 'trashed', 'body', '[]', CURRENT_TIMESTAMP)",
+		"INSERT INTO document_revisions (document, revision, name, body, tags, created_at) VALUES (2, 1, 'live', 'body', '[]', CURRENT_TIMESTAMP)",
+	}
+	for _, statement := range statements {
+		_, err := db.Exec(statement)
+		require.NoError(t, err, statement)
+	}
+
+	migrations, err := Migrations(DriverSQLite)
+	require.NoError(t, err)
+
+	// Reverting to the version before soft delete
+	_, err = MigrateDown(db, len(migrations)-6)
+	require.NoError(t, err)
+
+	counts := []struct {
+		query    string
+		expected int
+	}{
+		{query: "SELECT COUNT(*) FROM documents", expected: 1},
+		{query: "SELECT COUNT(*) FROM tags", expected: 1},
+		{query: "SELECT COUNT(*) FROM tags WHERE parent_id IS NOT NULL", expected: 0},
+		{query: "SELECT COUNT(*) FROM tag_aliases", expected: 0},
+		{query: "SELECT COUNT(*) FROM tags_documents", expected: 1},
+		{query: "SELECT COUNT(*) FROM document_revisions", expected: 1},
+	}
+	for _, count := range counts {
+		var actual int
+		require.NoError(t, db.Get(&actual, count.query))
+		require.Equal(t, count.expected, actual, count.query)
+	}
+}
@@ -0,0 +1,28 @@
+CREATE TABLE tags (
+	id       BIGSERIAL PRIMARY KEY,
+	name     TEXT NOT NULL,
+	assigned BOOLEAN NOT NULL,
+	UNIQUE(name)
+);
+
+CREATE TABLE documents (
+	id   BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	body TEXT NOT NULL,
+	UNIQUE(name)
+);
+
+CREATE TABLE tags_documents (
+	tag      BIGINT NOT NULL,
+	document BIGINT NOT NULL,
+	FOREIGN KEY(tag) REFERENCES tags(id) ON DELETE CASCADE,
+	FOREIGN KEY(document) REFERENCES documents(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "DOCUMENT_ID_M2M" ON "tags_documents" (
+	"document"
+);
+
+CREATE INDEX "TAG_ID_M2M" ON "tags_documents" (
+	"tag"
+);
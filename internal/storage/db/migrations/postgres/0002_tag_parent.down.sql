@@ -0,0 +1,2 @@
+DROP INDEX "TAG_PARENT_ID";
+ALTER TABLE tags DROP COLUMN parent_id;
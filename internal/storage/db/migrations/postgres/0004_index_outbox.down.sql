@@ -0,0 +1 @@
+DROP TABLE index_outbox;
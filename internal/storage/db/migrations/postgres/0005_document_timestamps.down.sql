@@ -0,0 +1,3 @@
+DROP INDEX "DOCUMENTS_CREATED_AT";
+ALTER TABLE documents DROP COLUMN updated_at;
+ALTER TABLE documents DROP COLUMN created_at;
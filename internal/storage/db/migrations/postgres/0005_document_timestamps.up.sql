@@ -0,0 +1,7 @@
+-- Time of existing documents is unknown so migration time is used
+ALTER TABLE documents ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE documents ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX "DOCUMENTS_CREATED_AT" ON "documents" (
+	"created_at"
+);
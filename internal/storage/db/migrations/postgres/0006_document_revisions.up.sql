@@ -0,0 +1,14 @@
+-- State of document after every change. Tags are stored as JSON array of names
+-- so revision stays readable after its tags are renamed or deleted.
+CREATE TABLE document_revisions (
+	id         BIGSERIAL PRIMARY KEY,
+	document   BIGINT NOT NULL,
+	revision   BIGINT NOT NULL,
+	name       TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	tags       TEXT NOT NULL,
+	author     TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	UNIQUE(document, revision),
+	FOREIGN KEY(document) REFERENCES documents(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+DROP VIEW live_tags;
+
+-- Items in trash are deleted because without deleted_at they would become visible again.
+-- Rows referencing them are removed by ON DELETE cascades of foreign keys.
+DELETE FROM documents WHERE deleted_at IS NOT NULL;
+DELETE FROM tags WHERE deleted_at IS NOT NULL;
+
+DROP INDEX "TAGS_DELETED_AT";
+DROP INDEX "DOCUMENTS_DELETED_AT";
+ALTER TABLE tags DROP COLUMN deleted_at;
+ALTER TABLE documents DROP COLUMN deleted_at;
@@ -0,0 +1,19 @@
+-- Deleted documents and tags stay in trash until they are restored or purged
+ALTER TABLE documents ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE tags ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX "DOCUMENTS_DELETED_AT" ON "documents" (
+	"deleted_at"
+);
+
+CREATE INDEX "TAGS_DELETED_AT" ON "tags" (
+	"deleted_at"
+);
+
+-- Tags which are not in trash. Parent in trash is hidden so its children are shown as roots
+-- while their parent_id is kept to restore hierarchy when parent is restored.
+CREATE VIEW live_tags AS
+SELECT tags.id, tags.name, tags.assigned, parents.id AS parent_id
+FROM tags
+LEFT JOIN tags AS parents ON parents.id = tags.parent_id AND parents.deleted_at IS NULL
+WHERE tags.deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Language selects analyzed fields document is indexed with. Documents created before
+-- languages were introduced have empty language which is detected on indexing.
+ALTER TABLE documents ADD COLUMN language TEXT NOT NULL DEFAULT '';
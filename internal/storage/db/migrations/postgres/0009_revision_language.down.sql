@@ -0,0 +1 @@
+ALTER TABLE document_revisions DROP COLUMN language;
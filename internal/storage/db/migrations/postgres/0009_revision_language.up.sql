@@ -0,0 +1,3 @@
+-- Language of document is restored with its revision. Revisions saved before languages were
+-- recorded have empty language, document restored from them gets language detected on indexing.
+ALTER TABLE document_revisions ADD COLUMN language TEXT NOT NULL DEFAULT '';
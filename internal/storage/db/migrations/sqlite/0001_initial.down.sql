@@ -0,0 +1,3 @@
+DROP TABLE tags_documents;
+DROP TABLE documents;
+DROP TABLE tags;
@@ -0,0 +1,38 @@
+-- IF NOT EXISTS lets databases created before migrations were introduced adopt this migration
+CREATE TABLE IF NOT EXISTS tags (
+	id   	 INTEGER PRIMARY KEY AUTOINCREMENT,
+	name 	 TEXT NOT NULL,
+	assigned BOOL NOT NULL,
+	UNIQUE(name)
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+	id   INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	body TEXT NOT NULL,
+	UNIQUE(name)
+);
+
+CREATE TABLE IF NOT EXISTS tags_documents (
+	tag INTEGER NOT NULL,
+	document INTEGER NOT NULL,
+	FOREIGN KEY(tag) REFERENCES tags(id) ON DELETE CASCADE,
+	FOREIGN KEY(document) REFERENCES documents(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "DOCUMENT_ID" ON "documents" (
+	"id"
+);
+
+CREATE INDEX IF NOT EXISTS "DOCUMENT_ID_M2M" ON "tags_documents" (
+	"document"
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "TAG_ID" ON "tags" (
+	"id",
+	"name"
+);
+
+CREATE INDEX IF NOT EXISTS "TAG_ID_M2M" ON "tags_documents" (
+	"tag"
+);
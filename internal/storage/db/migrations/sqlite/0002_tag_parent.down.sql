@@ -0,0 +1,18 @@
+-- Column referenced in foreign key can not be dropped so table is recreated without it
+CREATE TABLE tags_without_parent (
+	id   	 INTEGER PRIMARY KEY AUTOINCREMENT,
+	name 	 TEXT NOT NULL,
+	assigned BOOL NOT NULL,
+	UNIQUE(name)
+);
+
+INSERT INTO tags_without_parent (id, name, assigned) SELECT id, name, assigned FROM tags;
+
+DROP TABLE tags;
+
+ALTER TABLE tags_without_parent RENAME TO tags;
+
+CREATE UNIQUE INDEX "TAG_ID" ON "tags" (
+	"id",
+	"name"
+);
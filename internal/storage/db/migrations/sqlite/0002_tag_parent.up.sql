@@ -0,0 +1,5 @@
+ALTER TABLE tags ADD COLUMN parent_id INTEGER REFERENCES tags(id) ON DELETE SET NULL;
+
+CREATE INDEX "TAG_PARENT_ID" ON "tags" (
+	"parent_id"
+);
@@ -0,0 +1,11 @@
+CREATE TABLE tag_aliases (
+	id   INTEGER PRIMARY KEY AUTOINCREMENT,
+	tag  INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	UNIQUE(name),
+	FOREIGN KEY(tag) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "TAG_ALIAS_TAG_ID" ON "tag_aliases" (
+	"tag"
+);
@@ -0,0 +1,13 @@
+-- Documents which must be (re)indexed or deleted from index. No foreign key to documents
+-- because deleted documents must stay in outbox until they are deleted from index.
+CREATE TABLE index_outbox (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	document     INTEGER NOT NULL,
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	available_at INTEGER NOT NULL,
+	last_error   TEXT
+);
+
+CREATE INDEX "INDEX_OUTBOX_AVAILABLE_AT" ON "index_outbox" (
+	"available_at"
+);
@@ -0,0 +1,9 @@
+ALTER TABLE documents ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE documents ADD COLUMN updated_at TIMESTAMP;
+
+-- Time of existing documents is unknown so migration time is used
+UPDATE documents SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX "DOCUMENTS_CREATED_AT" ON "documents" (
+	"created_at"
+);
@@ -0,0 +1,18 @@
+DROP VIEW live_tags;
+
+-- Items in trash are deleted because without deleted_at they would become visible again.
+-- Rows referencing them are deleted explicitly because SQLite migrations run with foreign keys off,
+-- so cascades would not fire.
+DELETE FROM tags_documents
+WHERE document IN (SELECT id FROM documents WHERE deleted_at IS NOT NULL)
+OR tag IN (SELECT id FROM tags WHERE deleted_at IS NOT NULL);
+DELETE FROM document_revisions WHERE document IN (SELECT id FROM documents WHERE deleted_at IS NOT NULL);
+DELETE FROM tag_aliases WHERE tag IN (SELECT id FROM tags WHERE deleted_at IS NOT NULL);
+UPDATE tags SET parent_id = NULL WHERE parent_id IN (SELECT id FROM tags WHERE deleted_at IS NOT NULL);
+DELETE FROM documents WHERE deleted_at IS NOT NULL;
+DELETE FROM tags WHERE deleted_at IS NOT NULL;
+
+DROP INDEX "TAGS_DELETED_AT";
+DROP INDEX "DOCUMENTS_DELETED_AT";
+ALTER TABLE tags DROP COLUMN deleted_at;
+ALTER TABLE documents DROP COLUMN deleted_at;
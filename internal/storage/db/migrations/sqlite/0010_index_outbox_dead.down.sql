@@ -0,0 +1 @@
+ALTER TABLE index_outbox DROP COLUMN dead;
@@ -0,0 +1,2 @@
+-- Entries which failed too many times are not retried anymore, they are kept for verification
+ALTER TABLE index_outbox ADD COLUMN dead BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Opens PostgreSQL database and applies pending migrations
+func NewPostgresDb(dsn string) *sqlx.DB {
+	db := OpenPostgres(dsn)
+
+	if _, err := MigrateUp(db); err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// Opens PostgreSQL database without applying migrations
+func OpenPostgres(dsn string) *sqlx.DB {
+	db, err := sqlx.Open(DriverPostgres, dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	return db
+}
@@ -5,86 +5,25 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// Opens database and applies pending migrations
 func NewDb(path string) *sqlx.DB {
-	db, err := sqlx.Open("sqlite", "file:"+path+"?"+"_pragma=foreign_keys(1)&cache=shared")
-	if err != nil {
-		panic(err)
-	}
-
-	db.SetMaxOpenConns(1)
-
-	_, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS tags (
-		id   	 INTEGER PRIMARY KEY AUTOINCREMENT,
-		name 	 TEXT NOT NULL,
-		assigned BOOL NOT NULL,
-		UNIQUE(name)
-	)
-	`)
-	if err != nil {
-		panic(err)
-	}
+	db := Open(path)
 
-	_, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS documents (
-		id   INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		body TEXT NOT NULL,
-		UNIQUE(name)
-	)
-	`)
-	if err != nil {
-		panic(err)
-	}
-
-	_, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS tags_documents (
-		tag INTEGER NOT NULL,
-		document INTEGER NOT NULL,
-		FOREIGN KEY(tag) REFERENCES tags(id) ON DELETE CASCADE,
-		FOREIGN KEY(document) REFERENCES documents(id) ON DELETE CASCADE
-	)
-	`)
-	if err != nil {
+	if _, err := MigrateUp(db); err != nil {
 		panic(err)
 	}
 
-	_, err = db.Exec(`
-	CREATE UNIQUE INDEX IF NOT EXISTS "DOCUMENT_ID" ON "documents" (
-		"id"
-	)
-	`)
-	if err != nil {
-		panic(err)
-	}
-
-	_, err = db.Exec(`
-	CREATE INDEX IF NOT EXISTS "DOCUMENT_ID_M2M" ON "tags_documents" (
-		"document"
-	)
-	`)
-	if err != nil {
-		panic(err)
-	}
+	return db
+}
 
-	_, err = db.Exec(`
-	CREATE UNIQUE INDEX IF NOT EXISTS "TAG_ID" ON "tags" (
-		"id",
-		"name"
-	)
-	`)
+// Opens database without applying migrations
+func Open(path string) *sqlx.DB {
+	db, err := sqlx.Open(DriverSQLite, "file:"+path+"?"+"_pragma=foreign_keys(1)&cache=shared")
 	if err != nil {
 		panic(err)
 	}
 
-	_, err = db.Exec(`
-	CREATE INDEX IF NOT EXISTS "TAG_ID_M2M" ON "tags_documents" (
-		"tag"
-	)
-	`)
-	if err != nil {
-		panic(err)
-	}
+	db.SetMaxOpenConns(1)
 
 	return db
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	BulkOperationCreate = "create"
+	BulkOperationUpdate = "update"
+	BulkOperationDelete = "delete"
+)
+
+/*
+Operation of bulk request as it is passed by client. Document is CreateDocumentRequest for create,
+UpdateDocumentRequest for update and is absent for delete. ID is required for update and delete.
+*/
+type BulkOperationRequest struct {
+	Op       string          `json:"op"`
+	ID       ID              `json:"id"`
+	Document json.RawMessage `json:"document"`
+}
+
+// Bulk operation with decoded document, only request of its kind is set
+type BulkOperation struct {
+	Op     string
+	ID     ID
+	Create *CreateDocumentRequest
+	Update *UpdateDocumentRequest
+}
+
+// Decodes document of operation according to its kind
+func (request *BulkOperationRequest) Decode() (operation BulkOperation, err error) {
+	operation = BulkOperation{Op: request.Op, ID: request.ID}
+
+	switch request.Op {
+	case BulkOperationCreate:
+		operation.Create = &CreateDocumentRequest{}
+		if err := json.Unmarshal(request.Document, operation.Create); err != nil {
+			return operation, fmt.Errorf("unable to decode document: %w", err)
+		}
+	case BulkOperationUpdate:
+		if request.ID == 0 {
+			return operation, fmt.Errorf("id is required for %s", request.Op)
+		}
+		operation.Update = &UpdateDocumentRequest{}
+		if err := json.Unmarshal(request.Document, operation.Update); err != nil {
+			return operation, fmt.Errorf("unable to decode document: %w", err)
+		}
+		operation.Update.RemoveCommonTags()
+	case BulkOperationDelete:
+		if request.ID == 0 {
+			return operation, fmt.Errorf("id is required for %s", request.Op)
+		}
+	default:
+		return operation, fmt.Errorf("unknown operation '%s', expected %s, %s or %s", request.Op, BulkOperationCreate, BulkOperationUpdate, BulkOperationDelete)
+	}
+
+	return operation, nil
+}
+
+// Outcome of single bulk operation. ID is id of created, updated or deleted document.
+type BulkOperationResult struct {
+	ID  ID
+	Err error
+}
+
+type BulkItemResponse struct {
+	Op     string `json:"op"`
+	ID     ID     `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	// Response per operation in order of request
+	Items []BulkItemResponse `json:"items"`
+	// True if any operation failed
+	Errors bool `json:"errors"`
+}
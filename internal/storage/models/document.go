@@ -1,18 +1,29 @@
 package models
 
-import "gopkg.in/guregu/null.v4"
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
 
 type CreateDocumentRequest struct {
 	Name string        `json:"name" binding:"required"`
 	Body string        `json:"body" binding:"required"`
 	Tags []TagResponse `json:"tags"`
+	// Detected from body if empty
+	Language string `json:"language"`
+	// Author of change saved in document revision, passed in request header
+	Author null.String `json:"-"`
 }
 
 type UpdateDocumentRequest struct {
 	Name         null.String   `json:"name"`
 	Body         null.String   `json:"body"`
+	Language     null.String   `json:"language"`
 	TagsToAdd    []TagResponse `json:"tagsToAdd" binding:"unique"`
 	TagsToRemove []TagResponse `json:"tagsToRemove" binding:"unique"`
+	// Author of change saved in document revision, passed in request header
+	Author null.String `json:"-"`
 }
 
 /*
@@ -31,15 +42,25 @@ TagsToAdd := []int{1, 4}
 TagsToRemove := []int{5}
 */
 func (udr *UpdateDocumentRequest) RemoveCommonTags() {
-	tagsSliceToMap := func(tags []TagResponse) map[ID]TagResponse {
-		result := make(map[ID]TagResponse, len(tags))
+	// Tags passed by name (or alias) without ID are identified by name
+	type tagKey struct {
+		ID   ID
+		Name string
+	}
+
+	tagsSliceToMap := func(tags []TagResponse) map[tagKey]TagResponse {
+		result := make(map[tagKey]TagResponse, len(tags))
 		for _, tag := range tags {
-			result[tag.ID] = tag
+			key := tagKey{ID: tag.ID}
+			if tag.ID == 0 {
+				key.Name = tag.Name
+			}
+			result[key] = tag
 		}
 		return result
 	}
 
-	tagsMapToSlice := func(tagsMap map[ID]TagResponse) []TagResponse {
+	tagsMapToSlice := func(tagsMap map[tagKey]TagResponse) []TagResponse {
 		result := make([]TagResponse, 0, len(tagsMap))
 		for _, tag := range tagsMap {
 			result = append(result, tag)
@@ -65,6 +86,11 @@ type DocumentResponse struct {
 	Name string        `json:"name" db:"name"`
 	Body string        `json:"body" db:"body"`
 	Tags []TagResponse `json:"tags,omitempty"`
+	// Empty for documents created before languages were introduced
+	Language string `json:"language" db:"language"`
+
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
 func (documentResponse *DocumentResponse) TagNames() (tags []string) {
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"slices"
+	"unicode"
+)
+
+// Languages of documents. Every language has its own analyzed fields in search index.
+const (
+	LanguageRussian = "ru"
+	LanguageEnglish = "en"
+
+	// Language of documents without letters of any supported language
+	DefaultLanguage = LanguageRussian
+)
+
+var Languages = []string{LanguageRussian, LanguageEnglish}
+
+func IsSupportedLanguage(language string) bool {
+	return slices.Contains(Languages, language)
+}
+
+/*
+Detects language of text by counting letters of Cyrillic and Latin scripts.
+Text is considered English if it has more Latin letters than Cyrillic ones,
+otherwise it is Russian.
+*/
+func DetectLanguage(text string) string {
+	var cyrillic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	if latin > cyrillic {
+		return LanguageEnglish
+	}
+	return DefaultLanguage
+}
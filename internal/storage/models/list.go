@@ -0,0 +1,43 @@
+package models
+
+const (
+	// Page size of list endpoints if limit is not passed
+	DefaultListLimit = 50
+	// List requests with bigger limit are rejected
+	MaxListLimit = 500
+)
+
+/*
+Common parameters of list endpoints. Sort is a key prefixed with `-` for descending order, e.g. `-created`.
+Name prefix is matched case sensitively.
+*/
+type ListRequest struct {
+	Limit      int    `form:"limit"`
+	Offset     int    `form:"offset"`
+	Sort       string `form:"sort"`
+	NamePrefix string `form:"namePrefix"`
+}
+
+type ListDocumentsRequest struct {
+	ListRequest
+	// Only documents which have all of these tags are listed
+	HasTag []ID `form:"hasTag"`
+	// Only documents without tags are listed
+	Untagged bool `form:"untagged"`
+}
+
+type ListTagsRequest struct {
+	ListRequest
+	// Only tags with passed assigned flag are listed if set
+	Assigned *bool `form:"assigned"`
+	// Only tags assigned to at least this number of documents are listed
+	MinDocuments int `form:"minDocuments"`
+}
+
+// Page of list endpoint with total count of items matching request
+type ListResponse[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
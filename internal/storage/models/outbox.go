@@ -0,0 +1,15 @@
+package models
+
+import "gopkg.in/guregu/null.v4"
+
+// Pending change of document which must be applied to search index
+type OutboxEntry struct {
+	ID         ID  `json:"id" db:"id"`
+	DocumentID ID  `json:"documentId" db:"document"`
+	Attempts   int `json:"attempts" db:"attempts"`
+	// Unix time in milliseconds after which entry can be processed
+	AvailableAt int64       `json:"availableAt" db:"available_at"`
+	LastError   null.String `json:"lastError" db:"last_error"`
+	// Entry failed too many times and is not processed anymore
+	Dead bool `json:"dead" db:"dead"`
+}
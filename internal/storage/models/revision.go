@@ -0,0 +1,119 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Names of tags stored in database as JSON array
+type TagNames []string
+
+func (names TagNames) Value() (driver.Value, error) {
+	if names == nil {
+		names = TagNames{}
+	}
+	value, err := json.Marshal([]string(names))
+	if err != nil {
+		return nil, err
+	}
+	return string(value), nil
+}
+
+func (names *TagNames) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), names)
+	case []byte:
+		return json.Unmarshal(src, names)
+	default:
+		return fmt.Errorf("unable to scan %T into tag names", src)
+	}
+}
+
+// State of document after create, update or restore
+type DocumentRevision struct {
+	DocumentID ID          `json:"documentId" db:"document"`
+	Revision   int64       `json:"revision" db:"revision"`
+	Name       string      `json:"name" db:"name"`
+	Body       string      `json:"body" db:"body"`
+	Language   string      `json:"language" db:"language"` // empty in revisions saved before languages were recorded
+	Tags       TagNames    `json:"tags" db:"tags"`
+	Author     null.String `json:"author" db:"author"`
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+}
+
+type DocumentRevisionDiff struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Name of both revisions, empty if name was not changed
+	NameFrom string `json:"nameFrom,omitempty"`
+	NameTo   string `json:"nameTo,omitempty"`
+	// Language of both revisions, empty if language was not changed
+	LanguageFrom string `json:"languageFrom,omitempty"`
+	LanguageTo   string `json:"languageTo,omitempty"`
+	// Line-level unified diff of body, empty if body was not changed
+	Body        string   `json:"body"`
+	TagsAdded   []string `json:"tagsAdded"`
+	TagsRemoved []string `json:"tagsRemoved"`
+}
+
+// Returns changes which turn this revision into passed one
+func (from *DocumentRevision) Diff(to DocumentRevision) (diff DocumentRevisionDiff, err error) {
+	diff = DocumentRevisionDiff{
+		From:        from.Revision,
+		To:          to.Revision,
+		TagsAdded:   []string{},
+		TagsRemoved: []string{},
+	}
+
+	if from.Name != to.Name {
+		diff.NameFrom = from.Name
+		diff.NameTo = to.Name
+	}
+
+	if from.Language != to.Language {
+		diff.LanguageFrom = from.Language
+		diff.LanguageTo = to.Language
+	}
+
+	diff.Body, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.Body),
+		B:        difflib.SplitLines(to.Body),
+		FromFile: fmt.Sprintf("revision %d", from.Revision),
+		ToFile:   fmt.Sprintf("revision %d", to.Revision),
+		Context:  3,
+	})
+	if err != nil {
+		return diff, fmt.Errorf("unable to diff body: %w", err)
+	}
+
+	for _, tag := range to.Tags {
+		if !slices.Contains(from.Tags, tag) {
+			diff.TagsAdded = append(diff.TagsAdded, tag)
+		}
+	}
+	for _, tag := range from.Tags {
+		if !slices.Contains(to.Tags, tag) {
+			diff.TagsRemoved = append(diff.TagsRemoved, tag)
+		}
+	}
+	slices.Sort(diff.TagsAdded)
+	slices.Sort(diff.TagsRemoved)
+
+	return diff, nil
+}
+
+// Returns revision tags as tags without id so they are resolved by name (or alias if tag was merged)
+func (revision *DocumentRevision) TagRequests() []TagResponse {
+	tags := make([]TagResponse, 0, len(revision.Tags))
+	for _, name := range revision.Tags {
+		tags = append(tags, TagResponse{Name: name})
+	}
+	return tags
+}
@@ -1,17 +1,41 @@
 package models
 
+import "gopkg.in/guregu/null.v4"
+
 type ID = int64
 
 type CreateTagRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name     string   `json:"name" binding:"required"`
+	ParentID null.Int `json:"parentId"`
 }
 
 type UpdateTagRequest struct {
 	Name string `json:"name" binding:"required"`
+	// Parent is not changed if ParentID is null or absent. Zero ParentID detaches tag from its parent.
+	ParentID null.Int `json:"parentId"`
+}
+
+type MergeTagsRequest struct {
+	SourceIDs []ID `json:"sourceIds" binding:"required,min=1"`
 }
 
 type TagResponse struct {
-	ID       ID     `json:"id" form:"id" db:"id"`
-	Name     string `json:"name" form:"name" db:"name"`
-	Assigned bool   `json:"assigned" form:"assigned" db:"assigned"`
+	ID       ID       `json:"id" form:"id" db:"id"`
+	Name     string   `json:"name" form:"name" db:"name"`
+	Assigned bool     `json:"assigned" form:"assigned" db:"assigned"`
+	ParentID null.Int `json:"parentId" form:"parentId" db:"parent_id"`
+}
+
+type TagTreeNode struct {
+	TagResponse
+	Children []TagTreeNode `json:"children,omitempty"`
+}
+
+type CreateTagAliasRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type TagWithAliasesResponse struct {
+	TagResponse
+	Aliases []string `json:"aliases"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type TrashedDocument struct {
+	DocumentResponse
+	DeletedAt time.Time `json:"deletedAt" db:"deleted_at"`
+}
+
+type TrashedTag struct {
+	TagResponse
+	DeletedAt time.Time `json:"deletedAt" db:"deleted_at"`
+}
+
+// Documents and tags in trash, recently deleted first
+type TrashResponse struct {
+	Documents []TrashedDocument `json:"documents"`
+	Tags      []TrashedTag      `json:"tags"`
+}
+
+// Quantity of items deleted from trash permanently
+type PurgeResult struct {
+	Documents int64 `json:"documents"`
+	Tags      int64 `json:"tags"`
+}
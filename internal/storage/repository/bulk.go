@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// Number of bulk operations executed in one transaction
+const BulkChunkSize = 100
+
+/*
+Executes operations in transactions of BulkChunkSize operations. Every operation runs in its own savepoint
+so failed operation is rolled back alone and other operations of chunk are committed. Results are returned in order of operations,
+Err of result is set if operation failed. Chunk which can not be committed fails all of its operations and all following ones,
+the error is returned then too. Documents of committed chunks are enqueued for indexing.
+*/
+func (repository *DocumentRepository) Bulk(operations []models.BulkOperation) (results []models.BulkOperationResult, err error) {
+	results = make([]models.BulkOperationResult, 0, len(operations))
+	for start := 0; start < len(operations); start += BulkChunkSize {
+		chunkResults, err := repository.bulkChunk(operations[start:min(start+BulkChunkSize, len(operations))])
+		if err != nil {
+			for range operations[start:] {
+				results = append(results, models.BulkOperationResult{Err: fmt.Errorf("operation is not executed: %w", err)})
+			}
+			return results, err
+		}
+		results = append(results, chunkResults...)
+	}
+	return results, nil
+}
+
+func (repository *DocumentRepository) bulkChunk(operations []models.BulkOperation) (results []models.BulkOperationResult, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return results, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	results = make([]models.BulkOperationResult, 0, len(operations))
+	for _, operation := range operations {
+		if _, err := tx.Exec("SAVEPOINT bulk_operation"); err != nil {
+			return results, err
+		}
+
+		result := repository.bulkOperation(tx, operation)
+		if result.Err != nil {
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT bulk_operation"); err != nil {
+				return results, err
+			}
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT bulk_operation"); err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (repository *DocumentRepository) bulkOperation(tx *sqlx.Tx, operation models.BulkOperation) (result models.BulkOperationResult) {
+	result.ID = operation.ID
+	switch operation.Op {
+	case models.BulkOperationCreate:
+		var created models.DocumentResponse
+		created, result.Err = repository.create(tx, *operation.Create)
+		result.ID = created.ID
+	case models.BulkOperationUpdate:
+		result.Err = repository.update(tx, operation.ID, *operation.Update)
+	case models.BulkOperationDelete:
+		result.Err = repository.delete(tx, operation.ID)
+	default:
+		result.Err = fmt.Errorf("unknown bulk operation '%s'", operation.Op)
+	}
+	return result
+}
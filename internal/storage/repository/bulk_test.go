@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func Test_Bulk_Documents(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+	tagRepository := repository.tagRepository.(*TagRepository)
+	outboxRepository := NewOutboxRepository(repository.db)
+
+	tag, _ := tagRepository.Create(models.CreateTagRequest{Name: "tag"})
+	existing, _ := repository.Create(models.CreateDocumentRequest{Name: "existing", Body: "body"})
+	deleted, _ := repository.Create(models.CreateDocumentRequest{Name: "deleted", Body: "body"})
+	entries, err := outboxRepository.Fetch(100, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, outboxRepository.Complete(entries))
+
+	operations := []models.BulkOperation{
+		{Op: models.BulkOperationCreate, Create: &models.CreateDocumentRequest{Name: "created", Body: "body", Tags: []models.TagResponse{{Name: "tag"}}}},
+		{Op: models.BulkOperationCreate, Create: &models.CreateDocumentRequest{Name: "unknown tag", Body: "body", Tags: []models.TagResponse{{Name: "unknown"}}}},
+		{Op: models.BulkOperationCreate, Create: &models.CreateDocumentRequest{Name: "existing", Body: "duplicate name"}},
+		{Op: models.BulkOperationUpdate, ID: existing.ID, Update: &models.UpdateDocumentRequest{Body: null.StringFrom("updated"), TagsToAdd: []models.TagResponse{tag}}},
+		{Op: models.BulkOperationUpdate, ID: 1000, Update: &models.UpdateDocumentRequest{Body: null.StringFrom("updated")}},
+		{Op: models.BulkOperationDelete, ID: deleted.ID},
+		{Op: models.BulkOperationDelete, ID: deleted.ID},
+	}
+	// Operations after the first chunk are committed in their own transactions
+	for i := 0; i < BulkChunkSize; i++ {
+		operations = append(operations, models.BulkOperation{Op: models.BulkOperationCreate, Create: &models.CreateDocumentRequest{Name: fmt.Sprint(i), Body: "body"}})
+	}
+
+	results, err := repository.Bulk(operations)
+	require.NoError(t, err)
+	require.Len(t, results, len(operations))
+
+	require.NoError(t, results[0].Err)
+	require.NotZero(t, results[0].ID)
+	require.ErrorIs(t, results[1].Err, ErrTagNotFound)
+	require.Error(t, results[2].Err, "document names are unique")
+	require.NoError(t, results[3].Err)
+	require.Equal(t, existing.ID, results[3].ID)
+	require.ErrorIs(t, results[4].Err, ErrDocumentNotFound)
+	require.NoError(t, results[5].Err)
+	require.ErrorIs(t, results[6].Err, ErrDocumentNotFound, "document is already in trash")
+	for _, result := range results[7:] {
+		require.NoError(t, result.Err)
+	}
+
+	created, err := repository.Read(results[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, "created", created.Name)
+	require.Len(t, created.Tags, 1)
+
+	updated, err := repository.Read(existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, "updated", updated.Body)
+	require.Len(t, updated.Tags, 1)
+
+	documents, err := repository.List()
+	require.NoError(t, err)
+	require.Len(t, documents, 2+BulkChunkSize, "failed operations must be rolled back")
+
+	// Created, updated, deleted documents and documents of the second chunk are enqueued for indexing
+	entries, err = outboxRepository.Fetch(1000, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 3+BulkChunkSize)
+}
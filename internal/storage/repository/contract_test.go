@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Connection string of PostgreSQL database used by contract tests. Tests against PostgreSQL are skipped if it is not set.
+// All tables of this database are dropped and recreated by tests.
+const testPostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+type contractRepositories struct {
+	tags      *TagRepository
+	documents *DocumentRepository
+	outbox    *OutboxRepository
+	trash     *TrashRepository
+}
+
+// Runs test against every supported database with clean schema
+func runContract(t *testing.T, test func(t *testing.T, repositories contractRepositories)) {
+	backends := map[string]func(t *testing.T) *sqlx.DB{
+		db.DriverSQLite: func(t *testing.T) *sqlx.DB {
+			return db.NewDb(":memory:")
+		},
+		db.DriverPostgres: func(t *testing.T) *sqlx.DB {
+			dsn, ok := os.LookupEnv(testPostgresDSNEnv)
+			if !ok {
+				t.Skipf("%s is not set", testPostgresDSNEnv)
+			}
+			database := db.NewPostgresDb(dsn)
+			_, err := db.MigrateDown(database, 1000)
+			require.NoError(t, err)
+			_, err = db.MigrateUp(database)
+			require.NoError(t, err)
+			return database
+		},
+	}
+
+	for driver, open := range backends {
+		t.Run(driver, func(t *testing.T) {
+			database := open(t)
+			defer database.Close()
+
+			tagRepository := NewTagRepository(database)
+			test(t, contractRepositories{
+				tags:      tagRepository,
+				documents: NewDocumentRepository(database, tagRepository),
+				outbox:    NewOutboxRepository(database),
+				trash:     NewTrashRepository(database),
+			})
+		})
+	}
+}
+
+func Test_Contract_Tags(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		parent, err := repositories.tags.Create(models.CreateTagRequest{Name: "parent"})
+		require.NoError(t, err)
+		require.NotZero(t, parent.ID)
+
+		child, err := repositories.tags.Create(models.CreateTagRequest{Name: "child", ParentID: null.IntFrom(parent.ID)})
+		require.NoError(t, err)
+
+		read, err := repositories.tags.Read(child.ID)
+		require.NoError(t, err)
+		require.Equal(t, child, read)
+
+		_, err = repositories.tags.Update(parent.ID, models.UpdateTagRequest{Name: "parent", ParentID: null.IntFrom(child.ID)})
+		require.ErrorIs(t, err, ErrTagCycle)
+
+		updated, err := repositories.tags.Update(child.ID, models.UpdateTagRequest{Name: "renamed"})
+		require.NoError(t, err)
+		require.Equal(t, models.TagResponse{ID: child.ID, Name: "renamed", ParentID: null.IntFrom(parent.ID)}, updated)
+
+		tree, err := repositories.tags.Tree()
+		require.NoError(t, err)
+		require.Len(t, tree, 1)
+		require.Equal(t, updated, tree[0].Children[0].TagResponse)
+
+		require.NoError(t, repositories.tags.Delete(parent.ID))
+		orphan, err := repositories.tags.Read(child.ID)
+		require.NoError(t, err)
+		require.False(t, orphan.ParentID.Valid, "children of deleted tag must become roots")
+	})
+}
+
+func Test_Contract_Tag_Aliases(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		tag, _ := repositories.tags.Create(models.CreateTagRequest{Name: "Machine Learning"})
+
+		withAliases, err := repositories.tags.CreateAlias(tag.ID, models.CreateTagAliasRequest{Name: "ML"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ML"}, withAliases.Aliases)
+
+		_, err = repositories.tags.CreateAlias(tag.ID, models.CreateTagAliasRequest{Name: "Machine Learning"})
+		require.ErrorIs(t, err, ErrTagNameTaken)
+
+		resolved, err := repositories.tags.Resolve(nil, []models.TagResponse{{Name: "ML"}, {Name: "Machine Learning"}})
+		require.NoError(t, err)
+		require.Equal(t, []models.TagResponse{tag}, resolved)
+
+		aliases, err := repositories.tags.ResolveAliases([]string{"ML"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]models.TagResponse{"ML": tag}, aliases)
+	})
+}
+
+func Test_Contract_Documents(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		first, _ := repositories.tags.Create(models.CreateTagRequest{Name: "first"})
+		second, _ := repositories.tags.Create(models.CreateTagRequest{Name: "second"})
+
+		created, err := repositories.documents.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{{Name: "first"}}})
+		require.NoError(t, err)
+		require.NotZero(t, created.ID)
+
+		read, err := repositories.documents.Read(created.ID)
+		require.NoError(t, err)
+		require.Equal(t, "name", read.Name)
+		require.Len(t, read.Tags, 1)
+		require.True(t, read.Tags[0].Assigned)
+
+		updated, err := repositories.documents.Update(created.ID, models.UpdateDocumentRequest{
+			Body:         null.StringFrom("new body"),
+			TagsToAdd:    []models.TagResponse{second},
+			TagsToRemove: []models.TagResponse{first},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "new body", updated.Body)
+		require.Len(t, updated.Tags, 1)
+		require.Equal(t, second.ID, updated.Tags[0].ID)
+
+		unassigned, err := repositories.tags.Read(first.ID)
+		require.NoError(t, err)
+		require.False(t, unassigned.Assigned, "tag without documents must not be assigned")
+
+		IDs, err := repositories.documents.ListIDs()
+		require.NoError(t, err)
+		require.Equal(t, []models.ID{created.ID}, IDs)
+
+		require.NoError(t, repositories.documents.Delete(created.ID))
+		documents, err := repositories.documents.ReadMany([]models.ID{created.ID})
+		require.NoError(t, err)
+		require.Empty(t, documents)
+	})
+}
+
+func Test_Contract_Merge_And_Outbox(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		target, _ := repositories.tags.Create(models.CreateTagRequest{Name: "target"})
+		source, _ := repositories.tags.Create(models.CreateTagRequest{Name: "source"})
+		document, _ := repositories.documents.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{target, source}})
+
+		entries, err := repositories.outbox.Fetch(10, time.Now())
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.NoError(t, repositories.outbox.Complete(entries))
+
+		merged, err := repositories.tags.Merge(target.ID, models.MergeTagsRequest{SourceIDs: []models.ID{source.ID}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"source"}, merged.Aliases)
+
+		read, err := repositories.documents.Read(document.ID)
+		require.NoError(t, err)
+		require.Len(t, read.Tags, 1)
+		require.Equal(t, target.ID, read.Tags[0].ID)
+
+		entries, err = repositories.outbox.Fetch(10, time.Now())
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, document.ID, entries[0].DocumentID)
+
+		entries[0].Attempts = 1
+		entries[0].AvailableAt = time.Now().Add(time.Hour).UnixMilli()
+		entries[0].LastError = null.StringFrom("error")
+		require.NoError(t, repositories.outbox.Fail(entries))
+
+		entries, err = repositories.outbox.Fetch(10, time.Now())
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
+
+func Test_Contract_Revisions(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		tag, _ := repositories.tags.Create(models.CreateTagRequest{Name: "tag"})
+		document, err := repositories.documents.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{tag}, Author: null.StringFrom("author")})
+		require.NoError(t, err)
+
+		_, err = repositories.documents.Update(document.ID, models.UpdateDocumentRequest{Body: null.StringFrom("new body"), TagsToRemove: []models.TagResponse{tag}})
+		require.NoError(t, err)
+
+		revisions, err := repositories.documents.ListRevisions(document.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		require.Equal(t, models.TagNames{"tag"}, revisions[1].Tags)
+		require.Equal(t, null.StringFrom("author"), revisions[1].Author)
+		require.Equal(t, models.TagNames{}, revisions[0].Tags)
+
+		restored, err := repositories.documents.Restore(document.ID, 1, null.String{})
+		require.NoError(t, err)
+		require.Equal(t, "body", restored.Body)
+		require.Len(t, restored.Tags, 1)
+	})
+}
+
+func Test_Contract_Trash(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		tag, err := repositories.tags.Create(models.CreateTagRequest{Name: "tag"})
+		require.NoError(t, err)
+		document, err := repositories.documents.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{tag}})
+		require.NoError(t, err)
+
+		require.NoError(t, repositories.documents.Delete(document.ID))
+		require.NoError(t, repositories.tags.Delete(tag.ID))
+
+		trash, err := repositories.trash.List()
+		require.NoError(t, err)
+		require.Len(t, trash.Documents, 1)
+		require.Len(t, trash.Tags, 1)
+
+		require.NoError(t, repositories.trash.RestoreTag(tag.ID))
+		require.NoError(t, repositories.trash.RestoreDocument(document.ID))
+		read, err := repositories.documents.Read(document.ID)
+		require.NoError(t, err)
+		require.Len(t, read.Tags, 1)
+		require.True(t, read.Tags[0].Assigned)
+
+		require.NoError(t, repositories.documents.Delete(document.ID))
+		result, err := repositories.trash.Purge(time.Now().Add(time.Second))
+		require.NoError(t, err)
+		require.Equal(t, models.PurgeResult{Documents: 1}, result)
+	})
+}
+
+func Test_Contract_ListPage(t *testing.T) {
+	runContract(t, func(t *testing.T, repositories contractRepositories) {
+		sport, err := repositories.tags.Create(models.CreateTagRequest{Name: "спорт"})
+		require.NoError(t, err)
+		_, err = repositories.tags.Create(models.CreateTagRequest{Name: "погода"})
+		require.NoError(t, err)
+
+		football, err := repositories.documents.Create(models.CreateDocumentRequest{Name: "Футбол", Body: "body", Tags: []models.TagResponse{sport}})
+		require.NoError(t, err)
+		untagged, err := repositories.documents.Create(models.CreateDocumentRequest{Name: "Футзал", Body: "body"})
+		require.NoError(t, err)
+
+		documents, err := repositories.documents.ListPage(models.ListDocumentsRequest{
+			ListRequest: models.ListRequest{NamePrefix: "Фут", Sort: "-created", Limit: 1},
+			HasTag:      []models.ID{sport.ID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), documents.Total)
+		require.Equal(t, football.ID, documents.Items[0].ID)
+
+		documents, err = repositories.documents.ListPage(models.ListDocumentsRequest{Untagged: true})
+		require.NoError(t, err)
+		require.Equal(t, []models.ID{untagged.ID}, documentIDs(documents.Items))
+
+		assigned := true
+		tags, err := repositories.tags.ListPage(models.ListTagsRequest{Assigned: &assigned, MinDocuments: 1})
+		require.NoError(t, err)
+		require.Equal(t, []models.ID{sport.ID}, tagIDs(tags.Items))
+	})
+}
@@ -3,18 +3,22 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/jmoiron/sqlx"
 )
 
 var (
-	ErrTransactionOpen = errors.New("error on transaction opening")
+	ErrTransactionOpen     = errors.New("error on transaction opening")
+	ErrUnsupportedLanguage = errors.New("unsupported document language")
 )
 
 type TagAssigner interface {
+	Resolve(tx *sqlx.Tx, tags []models.TagResponse) (response []models.TagResponse, err error)
 	AssignForDocument(tx *sqlx.Tx, documentID models.ID, tags []models.TagResponse) (err error)
 	ListForDocument(tx *sqlx.Tx, documentID models.ID) (response []models.TagResponse, err error)
+	ListForDocuments(tx *sqlx.Tx, documentIDs []models.ID) (response map[models.ID][]models.TagResponse, err error)
 	DeleteForDocument(tx *sqlx.Tx, documentID models.ID, tags []models.TagResponse) (err error)
 }
 
@@ -37,29 +41,63 @@ func (repository *DocumentRepository) Create(request models.CreateDocumentReques
 	}
 	defer tx.Rollback()
 
-	res, err := tx.Exec("INSERT INTO documents VALUES (NULL, ?, ?)", request.Name, request.Body)
-	if err != nil {
+	if response, err = repository.create(tx, request); err != nil {
 		return response, err
 	}
 
-	documentID, err := res.LastInsertId()
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return response, err
 	}
 
-	if err := repository.tagRepository.AssignForDocument(tx, documentID, request.Tags); err != nil {
+	return response, nil
+}
+
+func (repository *DocumentRepository) create(tx *sqlx.Tx, request models.CreateDocumentRequest) (response models.DocumentResponse, err error) {
+	language := request.Language
+	if language == "" {
+		language = models.DetectLanguage(request.Body)
+	} else if !models.IsSupportedLanguage(language) {
+		return response, fmt.Errorf("%w: '%s'", ErrUnsupportedLanguage, language)
+	}
+
+	now := timestamp()
+	var documentID models.ID
+	if err := tx.Get(&documentID, tx.Rebind("INSERT INTO documents (name, body, language, created_at, updated_at) VALUES (?, ?, ?, ?, ?) RETURNING id"), request.Name, request.Body, language, now, now); err != nil {
 		return response, err
 	}
 
-	if err := tx.Commit(); err != nil {
+	// Tags may be passed by name or alias so they are resolved to canonical ones
+	tags := request.Tags
+	if len(tags) > 0 {
+		if tags, err = repository.tagRepository.Resolve(tx, tags); err != nil {
+			return response, err
+		}
+	}
+
+	if err := repository.tagRepository.AssignForDocument(tx, documentID, tags); err != nil {
+		return response, err
+	}
+	// Tags are returned as stored, they are assigned to created document now
+	for i := range tags {
+		tags[i].Assigned = true
+	}
+
+	if err := repository.appendRevision(tx, documentID, request.Author); err != nil {
+		return response, err
+	}
+
+	if err := enqueueIndexing(tx, documentID); err != nil {
 		return response, err
 	}
 
 	return models.DocumentResponse{
-		ID:   documentID,
-		Name: request.Name,
-		Body: request.Body,
-		Tags: request.Tags,
+		ID:        documentID,
+		Name:      request.Name,
+		Body:      request.Body,
+		Tags:      tags,
+		Language:  language,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}, nil
 }
 
@@ -71,13 +109,17 @@ func (repository *DocumentRepository) Read(id models.ID) (response models.Docume
 	}
 	defer tx.Rollback()
 
-	if err := tx.Get(&response, "SELECT id, name, body FROM documents WHERE id = ?", id); err != nil {
+	if err := tx.Get(&response, tx.Rebind("SELECT id, name, body, language, created_at, updated_at FROM documents WHERE id = ? AND deleted_at IS NULL"), id); err != nil {
 		return response, err
 	}
 
-	if err := repository.setDocumentTags(tx, &response); err != nil {
+	tags, err := repository.tagRepository.ListForDocument(tx, id)
+	if err != nil {
 		return response, err
 	}
+	if len(tags) > 0 {
+		response.Tags = tags
+	}
 
 	if err := tx.Commit(); err != nil {
 		return response, err
@@ -86,13 +128,21 @@ func (repository *DocumentRepository) Read(id models.ID) (response models.Docume
 	return response, nil
 }
 
-func (repository *DocumentRepository) setDocumentTags(tx *sqlx.Tx, documentResponse *models.DocumentResponse) (err error) {
-	tags, err := repository.tagRepository.ListForDocument(tx, documentResponse.ID)
+// Sets tags of every passed document, all tags are loaded with batched query
+func (repository *DocumentRepository) setDocumentsTags(tx *sqlx.Tx, documents []models.DocumentResponse) (err error) {
+	IDs := make([]models.ID, 0, len(documents))
+	for _, document := range documents {
+		IDs = append(IDs, document.ID)
+	}
+
+	tagsByDocument, err := repository.tagRepository.ListForDocuments(tx, IDs)
 	if err != nil {
 		return err
 	}
-	if len(tags) > 0 {
-		documentResponse.Tags = tags
+	for i := range documents {
+		if tags, ok := tagsByDocument[documents[i].ID]; ok {
+			documents[i].Tags = tags
+		}
 	}
 	return nil
 }
@@ -102,7 +152,7 @@ func (repository *DocumentRepository) ReadMany(IDs []models.ID) (response []mode
 		return response, nil
 	}
 
-	query, args, err := sqlx.In("SELECT id, name, body FROM documents WHERE id IN (?)", IDs)
+	query, args, err := sqlx.In("SELECT id, name, body, language, created_at, updated_at FROM documents WHERE id IN (?) AND deleted_at IS NULL", IDs)
 	if err != nil {
 		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
 	}
@@ -117,10 +167,8 @@ func (repository *DocumentRepository) ReadMany(IDs []models.ID) (response []mode
 		return response, err
 	}
 
-	for i := 0; i < len(response); i++ {
-		if err := repository.setDocumentTags(tx, &response[i]); err != nil {
-			return response, err
-		}
+	if err := repository.setDocumentsTags(tx, response); err != nil {
+		return response, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -137,35 +185,81 @@ func (repository *DocumentRepository) Update(id models.ID, updateRequest models.
 	}
 	defer tx.Rollback()
 
+	if err := repository.update(tx, id, updateRequest); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return repository.Read(id)
+}
+
+func (repository *DocumentRepository) update(tx *sqlx.Tx, id models.ID, updateRequest models.UpdateDocumentRequest) (err error) {
+	if err := repository.ensureRevision(tx, id); err != nil {
+		return err
+	}
+
 	if updateRequest.Name.Valid {
-		if _, err := tx.Exec("UPDATE documents SET name = ? WHERE id = ?", updateRequest.Name.String, id); err != nil {
-			return response, err
+		if _, err := tx.Exec(tx.Rebind("UPDATE documents SET name = ? WHERE id = ?"), updateRequest.Name.String, id); err != nil {
+			return err
 		}
 	}
 
 	if updateRequest.Body.Valid {
-		if _, err := tx.Exec("UPDATE documents SET body = ? WHERE id = ?", updateRequest.Body.String, id); err != nil {
-			return response, err
+		if _, err := tx.Exec(tx.Rebind("UPDATE documents SET body = ? WHERE id = ?"), updateRequest.Body.String, id); err != nil {
+			return err
+		}
+	}
+
+	// Language is kept on body change, so explicitly set language is not overwritten by detection
+	if updateRequest.Language.Valid {
+		if !models.IsSupportedLanguage(updateRequest.Language.String) {
+			return fmt.Errorf("%w: '%s'", ErrUnsupportedLanguage, updateRequest.Language.String)
+		}
+		if _, err := tx.Exec(tx.Rebind("UPDATE documents SET language = ? WHERE id = ?"), updateRequest.Language.String, id); err != nil {
+			return err
 		}
 	}
 
 	if len(updateRequest.TagsToAdd) > 0 {
-		if err := repository.tagRepository.AssignForDocument(tx, id, updateRequest.TagsToAdd); err != nil {
-			return response, err
+		tagsToAdd, err := repository.tagRepository.Resolve(tx, updateRequest.TagsToAdd)
+		if err != nil {
+			return err
+		}
+		if err := repository.tagRepository.AssignForDocument(tx, id, tagsToAdd); err != nil {
+			return err
 		}
 	}
 
 	if len(updateRequest.TagsToRemove) > 0 {
-		if err := repository.tagRepository.DeleteForDocument(tx, id, updateRequest.TagsToRemove); err != nil {
-			return response, err
+		tagsToRemove, err := repository.tagRepository.Resolve(tx, updateRequest.TagsToRemove)
+		if err != nil {
+			return err
+		}
+		if err := repository.tagRepository.DeleteForDocument(tx, id, tagsToRemove); err != nil {
+			return err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return response, err
+	if _, err := tx.Exec(tx.Rebind("UPDATE documents SET updated_at = ? WHERE id = ?"), timestamp(), id); err != nil {
+		return err
 	}
 
-	return repository.Read(id)
+	if err := repository.appendRevision(tx, id, updateRequest.Author); err != nil {
+		return err
+	}
+
+	return enqueueIndexing(tx, id)
+}
+
+/*
+Returns current time to be stored in database. Time is in UTC and truncated to microseconds
+because PostgreSQL doesn't store more precise time, so stored and returned values are equal on every database.
+*/
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
 }
 
 func (repository *DocumentRepository) Delete(id models.ID) (err error) {
@@ -175,7 +269,8 @@ func (repository *DocumentRepository) Delete(id models.ID) (err error) {
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec("DELETE FROM documents WHERE id = ?", id); err != nil {
+	// Deleting document which does not exist is not an error so repeated delete requests succeed
+	if err := repository.delete(tx, id); err != nil && !errors.Is(err, ErrDocumentNotFound) {
 		return err
 	}
 
@@ -186,6 +281,26 @@ func (repository *DocumentRepository) Delete(id models.ID) (err error) {
 	return nil
 }
 
+// Moves document to trash, returns wrapped ErrDocumentNotFound if there is no such document or it is already in trash
+func (repository *DocumentRepository) delete(tx *sqlx.Tx, id models.ID) (err error) {
+	// Document is moved to trash. Its tags are kept so they are assigned again if document is restored.
+	result, err := tx.Exec(tx.Rebind("UPDATE documents SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"), timestamp(), id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("%w: document with id '%d' does not exist", ErrDocumentNotFound, id)
+	}
+
+	if err := toggleDocumentTagsAssigned(tx, id); err != nil {
+		return err
+	}
+
+	return enqueueIndexing(tx, id)
+}
+
 func (repository *DocumentRepository) List() (response []models.DocumentResponse, err error) {
 	tx, err := repository.db.Beginx()
 	if err != nil {
@@ -193,12 +308,12 @@ func (repository *DocumentRepository) List() (response []models.DocumentResponse
 	}
 	defer tx.Rollback()
 
-	if err := tx.Select(&response, "SELECT id, name, body FROM documents ORDER BY name"); err != nil {
+	if err := tx.Select(&response, tx.Rebind("SELECT id, name, body, language, created_at, updated_at FROM documents WHERE deleted_at IS NULL ORDER BY name")); err != nil {
 		return response, err
 	}
 
-	for i := 0; i < len(response); i++ {
-		repository.setDocumentTags(tx, &response[i])
+	if err := repository.setDocumentsTags(tx, response); err != nil {
+		return response, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -208,30 +323,18 @@ func (repository *DocumentRepository) List() (response []models.DocumentResponse
 	return response, nil
 }
 
-func (repository *DocumentRepository) ListForTag(tagID models.ID) (response []models.DocumentResponse, err error) {
-	query := `
-	SELECT id, name, body 
-	FROM documents
-	WHERE id IN (
-		SELECT document 
-		FROM tags_documents
-		WHERE tag = ?
-	)
-	`
+// Returns ids of all documents in ascending order
+func (repository *DocumentRepository) ListIDs() (response []models.ID, err error) {
 	tx, err := repository.db.Beginx()
 	if err != nil {
 		return response, ErrTransactionOpen
 	}
 	defer tx.Rollback()
 
-	if err := tx.Select(&response, query, tagID); err != nil {
+	if err := tx.Select(&response, tx.Rebind("SELECT id FROM documents WHERE deleted_at IS NULL ORDER BY id")); err != nil {
 		return response, err
 	}
 
-	for i := 0; i < len(response); i++ {
-		repository.setDocumentTags(tx, &response[i])
-	}
-
 	if err := tx.Commit(); err != nil {
 		return response, err
 	}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// Lenta dataset used by index tests. Generated documents are benchmarked if it is absent.
+const benchmarkDatasetPath = "../../tests/index/lenta-ru-news.csv"
+
+// Number of generated documents and tags if dataset is absent
+const (
+	benchmarkGeneratedDocuments = 20000
+	benchmarkGeneratedTags      = 100
+)
+
+// Columns of dataset: url, title, text, topic, tags
+const (
+	benchmarkTitleColumn = 1
+	benchmarkTextColumn  = 2
+	benchmarkTopicColumn = 3
+	benchmarkTagColumn   = 4
+)
+
+// Returns dataset documents with their topic and tag as tags, header row is skipped
+func loadBenchmarkDataset(path string) (documents []models.DocumentResponse, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	csvReader.Comment = '#'
+	if _, err := csvReader.Read(); err != nil {
+		return nil, err
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, models.DocumentResponse{
+			Name: record[benchmarkTitleColumn],
+			Body: record[benchmarkTextColumn],
+			Tags: []models.TagResponse{{Name: record[benchmarkTopicColumn]}, {Name: record[benchmarkTagColumn]}},
+		})
+	}
+}
+
+/*
+Returns repository with dataset documents inserted directly into database
+and ids of inserted documents. Documents are inserted without revisions and outbox entries.
+*/
+func benchmarkDocumentRepository(b *testing.B) (*DocumentRepository, []models.ID, func()) {
+	b.Helper()
+
+	var documents []models.DocumentResponse
+	if _, err := os.Stat(benchmarkDatasetPath); err == nil {
+		if documents, err = loadBenchmarkDataset(benchmarkDatasetPath); err != nil {
+			b.Fatal(err)
+		}
+	} else {
+		b.Logf("%s not found, using %d generated documents", benchmarkDatasetPath, benchmarkGeneratedDocuments)
+		for i := 0; i < benchmarkGeneratedDocuments; i++ {
+			documents = append(documents, models.DocumentResponse{
+				Name: fmt.Sprintf("document %d", i),
+				Body: "body",
+				Tags: []models.TagResponse{
+					{Name: fmt.Sprintf("tag %d", i%benchmarkGeneratedTags)},
+					{Name: fmt.Sprintf("tag %d", (i+1)%benchmarkGeneratedTags)},
+				},
+			})
+		}
+	}
+
+	database := db.NewDb(":memory:")
+	repository := NewDocumentRepository(database, NewTagRepository(database))
+
+	tx := database.MustBegin()
+	tagIDs := map[string]models.ID{}
+	documentIDs := make([]models.ID, 0, len(documents))
+	now := timestamp()
+	for _, document := range documents {
+		var documentID models.ID
+		if err := tx.Get(&documentID, "INSERT INTO documents (name, body, created_at, updated_at) VALUES (?, ?, ?, ?) RETURNING id", document.Name, document.Body, now, now); err != nil {
+			// Dataset contains documents with equal titles
+			continue
+		}
+		documentIDs = append(documentIDs, documentID)
+
+		for _, tag := range document.Tags {
+			if tag.Name == "" {
+				continue
+			}
+			tagID, ok := tagIDs[tag.Name]
+			if !ok {
+				if err := tx.Get(&tagID, "INSERT INTO tags (name, assigned) VALUES (?, true) RETURNING id", tag.Name); err != nil {
+					b.Fatal(err)
+				}
+				tagIDs[tag.Name] = tagID
+			}
+			tx.MustExec("INSERT INTO tags_documents (tag, document) VALUES (?, ?)", tagID, documentID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	return repository, documentIDs, func() { database.Close() }
+}
+
+// Loads tags with query per document as read paths did before batched loader
+func setDocumentsTagsPerDocument(repository *DocumentRepository, tx *sqlx.Tx, documents []models.DocumentResponse) error {
+	for i := range documents {
+		tags, err := repository.tagRepository.ListForDocument(tx, documents[i].ID)
+		if err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			documents[i].Tags = tags
+		}
+	}
+	return nil
+}
+
+var tagLoaders = []struct {
+	name string
+	load func(repository *DocumentRepository, tx *sqlx.Tx, documents []models.DocumentResponse) error
+}{
+	{name: "per document", load: setDocumentsTagsPerDocument},
+	{name: "batched", load: (*DocumentRepository).setDocumentsTags},
+}
+
+// Reads documents of search result page, the main read path of search endpoint
+func Benchmark_ReadMany_Tags(b *testing.B) {
+	repository, documentIDs, cleanupFunc := benchmarkDocumentRepository(b)
+	defer cleanupFunc()
+
+	for _, pageSize := range []int{10, 100} {
+		IDs := documentIDs[len(documentIDs)/2 : len(documentIDs)/2+pageSize]
+		query, args, err := sqlx.In("SELECT id, name, body, created_at, updated_at FROM documents WHERE id IN (?)", IDs)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, loader := range tagLoaders {
+			b.Run(fmt.Sprintf("%s/%d", loader.name, pageSize), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tx := repository.db.MustBegin()
+					var documents []models.DocumentResponse
+					if err := tx.Select(&documents, query, args...); err != nil {
+						b.Fatal(err)
+					}
+					if err := loader.load(repository, tx, documents); err != nil {
+						b.Fatal(err)
+					}
+					tx.Rollback()
+				}
+			})
+		}
+	}
+}
+
+// Reads all documents as List does
+func Benchmark_List_Tags(b *testing.B) {
+	repository, _, cleanupFunc := benchmarkDocumentRepository(b)
+	defer cleanupFunc()
+
+	for _, loader := range tagLoaders {
+		b.Run(loader.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tx := repository.db.MustBegin()
+				var documents []models.DocumentResponse
+				if err := tx.Select(&documents, "SELECT id, name, body, created_at, updated_at FROM documents ORDER BY name"); err != nil {
+					b.Fatal(err)
+				}
+				if err := loader.load(repository, tx, documents); err != nil {
+					b.Fatal(err)
+				}
+				tx.Rollback()
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
@@ -35,7 +36,7 @@ func Test_Create_Document(t *testing.T) {
 
 	createdTags := make([]models.TagResponse, 0, len(testTags))
 	for _, tag := range testTags {
-		createdTag, _ := repository.tagRepository.Create(tag)
+		createdTag, _ := repository.tagRepository.(*TagRepository).Create(tag)
 		createdTags = append(createdTags, createdTag)
 	}
 
@@ -69,10 +70,13 @@ func Test_Create_Document(t *testing.T) {
 		require.Equal(
 			t,
 			models.DocumentResponse{
-				ID:   actual.ID,
-				Name: testDocuments[i].Name,
-				Body: testDocuments[i].Body,
-				Tags: testDocuments[i].Tags,
+				ID:        actual.ID,
+				Name:      testDocuments[i].Name,
+				Body:      testDocuments[i].Body,
+				Tags:      assignedTags(testDocuments[i].Tags...),
+				Language:  models.LanguageEnglish,
+				CreatedAt: actual.CreatedAt,
+				UpdatedAt: actual.UpdatedAt,
 			},
 			actual,
 		)
@@ -97,7 +101,7 @@ func Test_Read_Document(t *testing.T) {
 
 	createdTags := make([]models.TagResponse, 0, len(testTags))
 	for _, tag := range testTags {
-		createdTag, _ := repository.tagRepository.Create(tag)
+		createdTag, _ := repository.tagRepository.(*TagRepository).Create(tag)
 		createdTags = append(createdTags, createdTag)
 	}
 
@@ -155,7 +159,7 @@ func Test_ReadMany_Documents(t *testing.T) {
 
 	createdTags := make([]models.TagResponse, 0, len(testTags))
 	for _, tag := range testTags {
-		createdTag, _ := repository.tagRepository.Create(tag)
+		createdTag, _ := repository.tagRepository.(*TagRepository).Create(tag)
 		createdTags = append(createdTags, createdTag)
 	}
 
@@ -226,7 +230,7 @@ func Test_Update_Document(t *testing.T) {
 
 	createdTags := make([]models.TagResponse, 0, len(testTags))
 	for _, tag := range testTags {
-		createdTag, _ := repository.tagRepository.Create(tag)
+		createdTag, _ := repository.tagRepository.(*TagRepository).Create(tag)
 		createdTags = append(createdTags, createdTag)
 	}
 
@@ -238,13 +242,6 @@ func Test_Update_Document(t *testing.T) {
 
 	updatedDocumentName := "updated document"
 	updatedDocumentBody := "updated body"
-	expected := models.DocumentResponse{
-		ID:   createdDocument.ID,
-		Name: updatedDocumentName,
-		Body: updatedDocumentBody,
-		Tags: []models.TagResponse{createdTags[4], createdTags[5]},
-	}
-
 	actual, err := repository.Update(createdDocument.ID, models.UpdateDocumentRequest{
 		Name:         null.NewString(updatedDocumentName, true),
 		Body:         null.NewString(updatedDocumentBody, true),
@@ -252,6 +249,16 @@ func Test_Update_Document(t *testing.T) {
 		TagsToRemove: []models.TagResponse{createdTags[0], createdTags[1], createdTags[2], createdTags[3]},
 	})
 	require.NoError(t, err)
+
+	expected := models.DocumentResponse{
+		ID:        createdDocument.ID,
+		Name:      updatedDocumentName,
+		Body:      updatedDocumentBody,
+		Tags:      assignedTags(createdTags[4], createdTags[5]),
+		Language:  createdDocument.Language,
+		CreatedAt: createdDocument.CreatedAt,
+		UpdatedAt: actual.UpdatedAt,
+	}
 	require.Equal(
 		t,
 		expected,
@@ -292,7 +299,7 @@ func Test_List_Documents(t *testing.T) {
 
 	createdTags := make([]models.TagResponse, 0, len(testTags))
 	for _, tag := range testTags {
-		createdTag, _ := repository.tagRepository.Create(tag)
+		createdTag, _ := repository.tagRepository.(*TagRepository).Create(tag)
 		createdTags = append(createdTags, createdTag)
 	}
 
@@ -337,3 +344,89 @@ func Test_List_Documents(t *testing.T) {
 		actual,
 	)
 }
+
+func Test_Create_Document_With_Tag_Alias(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	tagRepository := repository.tagRepository.(*TagRepository)
+	machineLearning, _ := tagRepository.Create(models.CreateTagRequest{Name: "Machine Learning"})
+	tagRepository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "ML"})
+
+	createdDocument, err := repository.Create(models.CreateDocumentRequest{
+		Name: "test document",
+		Body: "test body",
+		Tags: []models.TagResponse{{Name: "ML"}, {Name: "Machine Learning"}},
+	})
+	require.NoError(t, err)
+	machineLearning.Assigned = true
+	require.Equal(t, []models.TagResponse{machineLearning}, createdDocument.Tags)
+
+	actual, err := repository.Read(createdDocument.ID)
+	require.NoError(t, err)
+	require.Equal(t, []models.TagResponse{machineLearning}, actual.Tags)
+}
+
+func Test_Document_Timestamps(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	before := time.Now().UTC()
+	createdDocument, err := repository.Create(models.CreateDocumentRequest{Name: "test name", Body: "test body"})
+	require.NoError(t, err)
+	require.False(t, createdDocument.CreatedAt.Before(before.Truncate(time.Microsecond)))
+	require.Equal(t, createdDocument.CreatedAt, createdDocument.UpdatedAt)
+
+	readDocument, err := repository.Read(createdDocument.ID)
+	require.NoError(t, err)
+	require.True(t, createdDocument.CreatedAt.Equal(readDocument.CreatedAt))
+	require.True(t, createdDocument.UpdatedAt.Equal(readDocument.UpdatedAt))
+
+	time.Sleep(time.Millisecond)
+	updatedDocument, err := repository.Update(createdDocument.ID, models.UpdateDocumentRequest{Body: null.StringFrom("updated body")})
+	require.NoError(t, err)
+	require.True(t, createdDocument.CreatedAt.Equal(updatedDocument.CreatedAt), "creation time must not change on update")
+	require.True(t, updatedDocument.UpdatedAt.After(createdDocument.UpdatedAt))
+}
+
+func Test_Document_Language(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	russianDocument, err := repository.Create(models.CreateDocumentRequest{Name: "Новости", Body: "Курс рубля вырос"})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageRussian, russianDocument.Language)
+
+	englishDocument, err := repository.Create(models.CreateDocumentRequest{Name: "Новости рынка", Body: "Ruble exchange rate has grown"})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageEnglish, englishDocument.Language)
+
+	explicitDocument, err := repository.Create(models.CreateDocumentRequest{Name: "iPhone", Body: "Обзор iPhone", Language: models.LanguageEnglish})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageEnglish, explicitDocument.Language)
+
+	_, err = repository.Create(models.CreateDocumentRequest{Name: "test name", Body: "test body", Language: "de"})
+	require.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	// Language is not detected again when body changes
+	updatedDocument, err := repository.Update(englishDocument.ID, models.UpdateDocumentRequest{Body: null.StringFrom("Курс рубля вырос")})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageEnglish, updatedDocument.Language)
+
+	updatedDocument, err = repository.Update(englishDocument.ID, models.UpdateDocumentRequest{Language: null.StringFrom(models.LanguageRussian)})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageRussian, updatedDocument.Language)
+
+	_, err = repository.Update(englishDocument.ID, models.UpdateDocumentRequest{Language: null.StringFrom("de")})
+	require.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
+
+// Returns copies of tags as they are stored after assigning them to document
+func assignedTags(tags ...models.TagResponse) []models.TagResponse {
+	assigned := make([]models.TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		tag.Assigned = true
+		assigned = append(assigned, tag)
+	}
+	return assigned
+}
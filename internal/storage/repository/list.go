@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidListRequest = errors.New("invalid list request")
+)
+
+// Sort keys of list requests mapped to columns
+var (
+	documentSortColumns = map[string]string{
+		"name":    "name",
+		"id":      "id",
+		"created": "created_at",
+		"updated": "updated_at",
+	}
+	tagSortColumns = map[string]string{
+		"name": "name",
+		"id":   "id",
+	}
+)
+
+// Conditions of WHERE clause joined with AND and their arguments
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (filter *listFilter) add(condition string, args ...interface{}) {
+	filter.conditions = append(filter.conditions, condition)
+	filter.args = append(filter.args, args...)
+}
+
+func (filter *listFilter) where() string {
+	if len(filter.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(filter.conditions, " AND ")
+}
+
+/*
+Fills default limit and sort of request and returns ORDER BY clause for it.
+Id is added as last sort column so pages do not overlap when sorted values are equal.
+Returned error wraps ErrInvalidListRequest.
+*/
+func listOrder(request *models.ListRequest, columns map[string]string, defaultSort string) (string, error) {
+	if request.Limit == 0 {
+		request.Limit = models.DefaultListLimit
+	}
+	if request.Limit < 0 || request.Limit > models.MaxListLimit {
+		return "", fmt.Errorf("%w: limit must be between 1 and %d, got %d", ErrInvalidListRequest, models.MaxListLimit, request.Limit)
+	}
+	if request.Offset < 0 {
+		return "", fmt.Errorf("%w: offset must not be negative, got %d", ErrInvalidListRequest, request.Offset)
+	}
+	if request.Sort == "" {
+		request.Sort = defaultSort
+	}
+
+	direction := "ASC"
+	key := request.Sort
+	if strings.HasPrefix(key, "-") {
+		direction = "DESC"
+		key = strings.TrimPrefix(key, "-")
+	}
+	column, ok := columns[key]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown sort key '%s'", ErrInvalidListRequest, request.Sort)
+	}
+
+	if column == "id" {
+		return fmt.Sprintf("ORDER BY id %s", direction), nil
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id %s", column, direction, direction), nil
+}
+
+// Adds condition matching names which start with passed prefix, case sensitive
+func (filter *listFilter) addNamePrefix(prefix string) {
+	if prefix == "" {
+		return
+	}
+	// SUBSTR counts characters on every supported database, LIKE would need escaping and differs in case sensitivity
+	filter.add("SUBSTR(name, 1, ?) = ?", utf8.RuneCountInString(prefix), prefix)
+}
+
+// Returns page of documents matching request, tags of every document are included
+func (repository *DocumentRepository) ListPage(request models.ListDocumentsRequest) (response models.ListResponse[models.DocumentResponse], err error) {
+	orderBy, err := listOrder(&request.ListRequest, documentSortColumns, "name")
+	if err != nil {
+		return response, err
+	}
+	if request.Untagged && len(request.HasTag) > 0 {
+		return response, fmt.Errorf("%w: untagged and hasTag can not be used together", ErrInvalidListRequest)
+	}
+
+	filter := listFilter{}
+	filter.add("deleted_at IS NULL")
+	filter.addNamePrefix(request.NamePrefix)
+	if len(request.HasTag) > 0 {
+		tagIDs := slices.Clone(request.HasTag)
+		slices.Sort(tagIDs)
+		tagIDs = slices.Compact(tagIDs)
+		filter.add(`id IN (
+			SELECT tags_documents.document
+			FROM tags_documents JOIN live_tags ON live_tags.id = tags_documents.tag
+			WHERE tags_documents.tag IN (?)
+			GROUP BY tags_documents.document
+			HAVING COUNT(DISTINCT tags_documents.tag) = ?
+		)`, tagIDs, len(tagIDs))
+	}
+	if request.Untagged {
+		filter.add(`NOT EXISTS (
+			SELECT 1
+			FROM tags_documents JOIN live_tags ON live_tags.id = tags_documents.tag
+			WHERE tags_documents.document = documents.id
+		)`)
+	}
+
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	response = models.ListResponse[models.DocumentResponse]{
+		Items:  []models.DocumentResponse{},
+		Limit:  request.Limit,
+		Offset: request.Offset,
+	}
+
+	if err := selectIn(tx, &response.Total, false, "SELECT COUNT(*) FROM documents "+filter.where(), filter.args...); err != nil {
+		return response, err
+	}
+
+	query := fmt.Sprintf("SELECT id, name, body, language, created_at, updated_at FROM documents %s %s LIMIT ? OFFSET ?", filter.where(), orderBy)
+	if err := selectIn(tx, &response.Items, true, query, append(filter.args, request.Limit, request.Offset)...); err != nil {
+		return response, err
+	}
+
+	if err := repository.setDocumentsTags(tx, response.Items); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Returns page of tags matching request
+func (repository *TagRepository) ListPage(request models.ListTagsRequest) (response models.ListResponse[models.TagResponse], err error) {
+	orderBy, err := listOrder(&request.ListRequest, tagSortColumns, "name")
+	if err != nil {
+		return response, err
+	}
+	if request.MinDocuments < 0 {
+		return response, fmt.Errorf("%w: minDocuments must not be negative, got %d", ErrInvalidListRequest, request.MinDocuments)
+	}
+
+	filter := listFilter{}
+	filter.addNamePrefix(request.NamePrefix)
+	if request.Assigned != nil {
+		filter.add("assigned = ?", *request.Assigned)
+	}
+	if request.MinDocuments > 0 {
+		filter.add(`(
+			SELECT COUNT(*)
+			FROM tags_documents JOIN documents ON documents.id = tags_documents.document
+			WHERE tags_documents.tag = live_tags.id AND documents.deleted_at IS NULL
+		) >= ?`, request.MinDocuments)
+	}
+
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	response = models.ListResponse[models.TagResponse]{
+		Items:  []models.TagResponse{},
+		Limit:  request.Limit,
+		Offset: request.Offset,
+	}
+
+	if err := selectIn(tx, &response.Total, false, "SELECT COUNT(*) FROM live_tags "+filter.where(), filter.args...); err != nil {
+		return response, err
+	}
+
+	query := fmt.Sprintf("SELECT id, name, assigned, parent_id FROM live_tags %s %s LIMIT ? OFFSET ?", filter.where(), orderBy)
+	if err := selectIn(tx, &response.Items, true, query, append(filter.args, request.Limit, request.Offset)...); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Expands slice arguments of query with sqlx.In, rebinds it and scans all rows into dest if many is true or single row otherwise
+func selectIn(tx *sqlx.Tx, dest interface{}, many bool, query string, args ...interface{}) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	if many {
+		return tx.Select(dest, tx.Rebind(query), args...)
+	}
+	return tx.Get(dest, tx.Rebind(query), args...)
+}
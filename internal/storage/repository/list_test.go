@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func documentIDs(documents []models.DocumentResponse) []models.ID {
+	IDs := make([]models.ID, 0, len(documents))
+	for _, document := range documents {
+		IDs = append(IDs, document.ID)
+	}
+	return IDs
+}
+
+func tagIDs(tags []models.TagResponse) []models.ID {
+	IDs := make([]models.ID, 0, len(tags))
+	for _, tag := range tags {
+		IDs = append(IDs, tag.ID)
+	}
+	return IDs
+}
+
+func Test_ListPage_Documents(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+	tagRepository := repository.tagRepository.(*TagRepository)
+
+	sport, _ := tagRepository.Create(models.CreateTagRequest{Name: "спорт"})
+	news, _ := tagRepository.Create(models.CreateTagRequest{Name: "новости"})
+	trashed, _ := tagRepository.Create(models.CreateTagRequest{Name: "удалённый"})
+
+	football, _ := repository.Create(models.CreateDocumentRequest{Name: "Футбол", Body: "body", Tags: []models.TagResponse{sport, news}})
+	hockey, _ := repository.Create(models.CreateDocumentRequest{Name: "Хоккей", Body: "body", Tags: []models.TagResponse{sport}})
+	weather, _ := repository.Create(models.CreateDocumentRequest{Name: "Погода", Body: "body", Tags: []models.TagResponse{trashed}})
+	futsal, _ := repository.Create(models.CreateDocumentRequest{Name: "Футзал", Body: "body"})
+	deleted, _ := repository.Create(models.CreateDocumentRequest{Name: "Футбол вчера", Body: "body", Tags: []models.TagResponse{sport}})
+	require.NoError(t, repository.Delete(deleted.ID))
+	require.NoError(t, tagRepository.Delete(trashed.ID))
+
+	testCases := []struct {
+		name          string
+		request       models.ListDocumentsRequest
+		expectedIDs   []models.ID
+		expectedTotal int64
+	}{
+		{
+			name:          "default sort by name",
+			expectedIDs:   []models.ID{weather.ID, football.ID, futsal.ID, hockey.ID},
+			expectedTotal: 4,
+		},
+		{
+			name:          "limit and offset",
+			request:       models.ListDocumentsRequest{ListRequest: models.ListRequest{Limit: 2, Offset: 1}},
+			expectedIDs:   []models.ID{football.ID, futsal.ID},
+			expectedTotal: 4,
+		},
+		{
+			name:          "offset after the last",
+			request:       models.ListDocumentsRequest{ListRequest: models.ListRequest{Offset: 10}},
+			expectedIDs:   []models.ID{},
+			expectedTotal: 4,
+		},
+		{
+			name:          "sort by created descending",
+			request:       models.ListDocumentsRequest{ListRequest: models.ListRequest{Sort: "-created"}},
+			expectedIDs:   []models.ID{futsal.ID, weather.ID, hockey.ID, football.ID},
+			expectedTotal: 4,
+		},
+		{
+			name:          "name prefix",
+			request:       models.ListDocumentsRequest{ListRequest: models.ListRequest{NamePrefix: "Фут", Sort: "id"}},
+			expectedIDs:   []models.ID{football.ID, futsal.ID},
+			expectedTotal: 2,
+		},
+		{
+			name:          "name prefix is case sensitive",
+			request:       models.ListDocumentsRequest{ListRequest: models.ListRequest{NamePrefix: "фут"}},
+			expectedIDs:   []models.ID{},
+			expectedTotal: 0,
+		},
+		{
+			name:          "has tag",
+			request:       models.ListDocumentsRequest{HasTag: []models.ID{sport.ID}, ListRequest: models.ListRequest{Sort: "id"}},
+			expectedIDs:   []models.ID{football.ID, hockey.ID},
+			expectedTotal: 2,
+		},
+		{
+			name:          "has all tags",
+			request:       models.ListDocumentsRequest{HasTag: []models.ID{sport.ID, news.ID, sport.ID}},
+			expectedIDs:   []models.ID{football.ID},
+			expectedTotal: 1,
+		},
+		{
+			name:          "untagged includes documents with tags in trash",
+			request:       models.ListDocumentsRequest{Untagged: true, ListRequest: models.ListRequest{Sort: "-id"}},
+			expectedIDs:   []models.ID{futsal.ID, weather.ID},
+			expectedTotal: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		response, err := repository.ListPage(testCase.request)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.expectedIDs, documentIDs(response.Items), testCase.name)
+		require.Equal(t, testCase.expectedTotal, response.Total, testCase.name)
+	}
+
+	response, err := repository.ListPage(models.ListDocumentsRequest{HasTag: []models.ID{news.ID}})
+	require.NoError(t, err)
+	require.Equal(t, models.DefaultListLimit, response.Limit)
+	require.Len(t, response.Items[0].Tags, 2, "documents are listed with tags")
+}
+
+func Test_ListPage_Documents_Invalid(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	for _, request := range []models.ListDocumentsRequest{
+		{ListRequest: models.ListRequest{Limit: -1}},
+		{ListRequest: models.ListRequest{Limit: models.MaxListLimit + 1}},
+		{ListRequest: models.ListRequest{Offset: -1}},
+		{ListRequest: models.ListRequest{Sort: "body"}},
+		{ListRequest: models.ListRequest{Sort: "name; DROP TABLE documents"}},
+		{Untagged: true, HasTag: []models.ID{1}},
+	} {
+		_, err := repository.ListPage(request)
+		require.ErrorIs(t, err, ErrInvalidListRequest, "%+v", request)
+	}
+}
+
+func Test_ListPage_Tags(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+	documentRepository := NewDocumentRepository(repository.db, repository)
+
+	sport, _ := repository.Create(models.CreateTagRequest{Name: "спорт"})
+	football, _ := repository.Create(models.CreateTagRequest{Name: "футбол", ParentID: null.IntFrom(sport.ID)})
+	futsal, _ := repository.Create(models.CreateTagRequest{Name: "футзал"})
+	trashed, _ := repository.Create(models.CreateTagRequest{Name: "футбол старый"})
+	require.NoError(t, repository.Delete(trashed.ID))
+
+	documentRepository.Create(models.CreateDocumentRequest{Name: "1", Body: "body", Tags: []models.TagResponse{sport, football}})
+	documentRepository.Create(models.CreateDocumentRequest{Name: "2", Body: "body", Tags: []models.TagResponse{sport}})
+	deleted, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "3", Body: "body", Tags: []models.TagResponse{football}})
+	require.NoError(t, documentRepository.Delete(deleted.ID))
+
+	assigned, unassigned := true, false
+	testCases := []struct {
+		name          string
+		request       models.ListTagsRequest
+		expectedIDs   []models.ID
+		expectedTotal int64
+	}{
+		{
+			name:          "default sort by name",
+			expectedIDs:   []models.ID{sport.ID, football.ID, futsal.ID},
+			expectedTotal: 3,
+		},
+		{
+			name:          "sort by id descending with limit",
+			request:       models.ListTagsRequest{ListRequest: models.ListRequest{Sort: "-id", Limit: 2}},
+			expectedIDs:   []models.ID{futsal.ID, football.ID},
+			expectedTotal: 3,
+		},
+		{
+			name:          "name prefix",
+			request:       models.ListTagsRequest{ListRequest: models.ListRequest{NamePrefix: "фут"}},
+			expectedIDs:   []models.ID{football.ID, futsal.ID},
+			expectedTotal: 2,
+		},
+		{
+			name:          "assigned",
+			request:       models.ListTagsRequest{Assigned: &assigned},
+			expectedIDs:   []models.ID{sport.ID, football.ID},
+			expectedTotal: 2,
+		},
+		{
+			name:          "not assigned",
+			request:       models.ListTagsRequest{Assigned: &unassigned},
+			expectedIDs:   []models.ID{futsal.ID},
+			expectedTotal: 1,
+		},
+		{
+			name:          "min documents does not count documents in trash",
+			request:       models.ListTagsRequest{MinDocuments: 2},
+			expectedIDs:   []models.ID{sport.ID},
+			expectedTotal: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		response, err := repository.ListPage(testCase.request)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.expectedIDs, tagIDs(response.Items), testCase.name)
+		require.Equal(t, testCase.expectedTotal, response.Total, testCase.name)
+	}
+
+	_, err := repository.ListPage(models.ListTagsRequest{MinDocuments: -1})
+	require.ErrorIs(t, err, ErrInvalidListRequest)
+}
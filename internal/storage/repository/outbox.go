@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+Outbox keeps documents which were changed in database but not yet in search index.
+Entries are written in the same transaction as the change itself, so committed change
+always has an entry and index can be synced by worker even after crash or restart.
+*/
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// Returns up to limit entries which are available for processing at passed time, oldest first. Dead entries are skipped
+func (repository *OutboxRepository) Fetch(limit int, now time.Time) (response []models.OutboxEntry, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT id, document, attempts, available_at, last_error, dead
+	FROM index_outbox
+	WHERE dead = FALSE AND available_at <= ?
+	ORDER BY id
+	LIMIT ?
+	`
+	if err := tx.Select(&response, tx.Rebind(query), now.UnixMilli(), limit); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+/*
+Deletes processed entries. Entries are deleted by their own id, so entries which were added
+for the same documents while batch was processed stay in outbox and will be processed later.
+*/
+func (repository *OutboxRepository) Complete(entries []models.OutboxEntry) (err error) {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	IDs := make([]models.ID, 0, len(entries))
+	for _, entry := range entries {
+		IDs = append(IDs, entry.ID)
+	}
+
+	query, args, err := sqlx.In("DELETE FROM index_outbox WHERE id IN (?)", IDs)
+	if err != nil {
+		return fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Saves attempts, availability time, last error and dead flag of entries which processing failed
+func (repository *OutboxRepository) Fail(entries []models.OutboxEntry) (err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		_, err := tx.Exec(
+			tx.Rebind("UPDATE index_outbox SET attempts = ?, available_at = ?, last_error = ?, dead = ? WHERE id = ?"),
+			entry.Attempts, entry.AvailableAt, entry.LastError, entry.Dead, entry.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Returns entries which are not processed anymore because they failed too many times, oldest first
+func (repository *OutboxRepository) ListDead() (response []models.OutboxEntry, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT id, document, attempts, available_at, last_error, dead
+	FROM index_outbox
+	WHERE dead = TRUE
+	ORDER BY id
+	`
+	if err := tx.Select(&response, query); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Makes dead entries available for processing again with reset attempts
+func (repository *OutboxRepository) Revive() (err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		tx.Rebind("UPDATE index_outbox SET dead = FALSE, attempts = 0, available_at = ? WHERE dead = TRUE"),
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Adds documents to outbox inside of transaction which changes them
+func enqueueIndexing(tx *sqlx.Tx, documentIDs ...models.ID) (err error) {
+	now := time.Now().UnixMilli()
+	for _, documentID := range documentIDs {
+		if _, err := tx.Exec(tx.Rebind("INSERT INTO index_outbox (document, available_at) VALUES (?, ?)"), documentID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds documents to which tag with passed id or any of its descendants is assigned to outbox
+func enqueueTagTreeIndexing(tx *sqlx.Tx, tagID models.ID) (err error) {
+	query := tagSubtreeCTE + `
+	INSERT INTO index_outbox (document, available_at)
+	SELECT DISTINCT document, CAST(? AS BIGINT)
+	FROM tags_documents
+	WHERE tag IN (SELECT id FROM subtree)
+	`
+	_, err = tx.Exec(tx.Rebind(query), tagID, time.Now().UnixMilli())
+	return err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func Test_Document_Changes_Enqueued(t *testing.T) {
+	documentRepository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	repository := NewOutboxRepository(documentRepository.db)
+
+	document, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "name", Body: "body"})
+	documentRepository.Update(document.ID, models.UpdateDocumentRequest{Body: null.StringFrom("new body")})
+	documentRepository.Delete(document.ID)
+
+	actual, err := repository.Fetch(10, time.Now())
+	require.NoError(t, err)
+	require.Len(t, actual, 3, "every change must be enqueued")
+	for _, entry := range actual {
+		require.Equal(t, document.ID, entry.DocumentID)
+	}
+}
+
+func Test_Outbox_Fail_And_Complete(t *testing.T) {
+	documentRepository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	repository := NewOutboxRepository(documentRepository.db)
+
+	first, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "first", Body: "body"})
+	second, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "second", Body: "body"})
+
+	now := time.Now()
+	entries, err := repository.Fetch(1, now)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, first.ID, entries[0].DocumentID)
+
+	entries[0].Attempts = 1
+	entries[0].AvailableAt = now.Add(time.Minute).UnixMilli()
+	entries[0].LastError = null.StringFrom("index is unavailable")
+	require.NoError(t, repository.Fail(entries))
+
+	entries, err = repository.Fetch(10, now)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "failed entry must not be available before retry time")
+	require.Equal(t, second.ID, entries[0].DocumentID)
+	require.NoError(t, repository.Complete(entries))
+
+	entries, err = repository.Fetch(10, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, first.ID, entries[0].DocumentID)
+	require.Equal(t, 1, entries[0].Attempts)
+	require.Equal(t, null.StringFrom("index is unavailable"), entries[0].LastError)
+}
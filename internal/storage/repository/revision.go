@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v4"
+)
+
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrRevisionNotFound = errors.New("document revision not found")
+)
+
+// Returns revisions of document, the latest first
+func (repository *DocumentRepository) ListRevisions(documentID models.ID) (response []models.DocumentRevision, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if err := checkDocumentExists(tx, documentID); err != nil {
+		return response, err
+	}
+
+	response = []models.DocumentRevision{}
+	query := `
+	SELECT document, revision, name, body, language, tags, author, created_at
+	FROM document_revisions
+	WHERE document = ?
+	ORDER BY revision DESC
+	`
+	if err := tx.Select(&response, tx.Rebind(query), documentID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (repository *DocumentRepository) ReadRevision(documentID models.ID, revision int64) (response models.DocumentRevision, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if response, err = readRevision(tx, documentID, revision); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+/*
+Sets name, body, language and tags of document to ones of passed revision. Restore is saved as new revision.
+Tags are resolved by names so tags which were merged since revision are replaced with merge targets.
+Returns wrapped ErrTagNotFound if any tag of revision does not exist anymore.
+*/
+func (repository *DocumentRepository) Restore(documentID models.ID, revision int64, author null.String) (response models.DocumentResponse, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if err := checkDocumentExists(tx, documentID); err != nil {
+		return response, err
+	}
+
+	restored, err := readRevision(tx, documentID, revision)
+	if err != nil {
+		return response, err
+	}
+
+	query := "UPDATE documents SET name = ?, body = ?, language = ?, updated_at = ? WHERE id = ?"
+	if _, err := tx.Exec(tx.Rebind(query), restored.Name, restored.Body, restored.Language, timestamp(), documentID); err != nil {
+		return response, err
+	}
+
+	var tags []models.TagResponse
+	if len(restored.Tags) > 0 {
+		if tags, err = repository.tagRepository.Resolve(tx, restored.TagRequests()); err != nil {
+			return response, err
+		}
+	}
+
+	currentTags, err := repository.tagRepository.ListForDocument(tx, documentID)
+	if err != nil {
+		return response, err
+	}
+
+	var tagsToRemove, tagsToAdd []models.TagResponse
+	for _, tag := range currentTags {
+		if !slices.ContainsFunc(tags, func(restoredTag models.TagResponse) bool { return restoredTag.ID == tag.ID }) {
+			tagsToRemove = append(tagsToRemove, tag)
+		}
+	}
+	for _, tag := range tags {
+		if !slices.ContainsFunc(currentTags, func(currentTag models.TagResponse) bool { return currentTag.ID == tag.ID }) {
+			tagsToAdd = append(tagsToAdd, tag)
+		}
+	}
+
+	if len(tagsToRemove) > 0 {
+		if err := repository.tagRepository.DeleteForDocument(tx, documentID, tagsToRemove); err != nil {
+			return response, err
+		}
+	}
+	if err := repository.tagRepository.AssignForDocument(tx, documentID, tagsToAdd); err != nil {
+		return response, err
+	}
+
+	if err := repository.appendRevision(tx, documentID, author); err != nil {
+		return response, err
+	}
+
+	if err := enqueueIndexing(tx, documentID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return repository.Read(documentID)
+}
+
+// Saves current state of document as its next revision
+func (repository *DocumentRepository) appendRevision(tx *sqlx.Tx, documentID models.ID, author null.String) (err error) {
+	var document models.DocumentResponse
+	if err := tx.Get(&document, tx.Rebind("SELECT id, name, body, language FROM documents WHERE id = ? AND deleted_at IS NULL"), documentID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: document with id '%d' does not exist", ErrDocumentNotFound, documentID)
+	} else if err != nil {
+		return err
+	}
+
+	tags, err := repository.tagRepository.ListForDocument(tx, documentID)
+	if err != nil {
+		return err
+	}
+	tagNames := make(models.TagNames, 0, len(tags))
+	for _, tag := range tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	slices.Sort(tagNames)
+
+	var revision int64
+	if err := tx.Get(&revision, tx.Rebind("SELECT COALESCE(MAX(revision), 0) + 1 FROM document_revisions WHERE document = ?"), documentID); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO document_revisions (document, revision, name, body, language, tags, author, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.Exec(tx.Rebind(query), documentID, revision, document.Name, document.Body, document.Language, tagNames, author, timestamp()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+Saves current state of document as its first revision if document has no revisions.
+Documents created before revisions were introduced get their state preserved before first update.
+*/
+func (repository *DocumentRepository) ensureRevision(tx *sqlx.Tx, documentID models.ID) (err error) {
+	var count int
+	if err := tx.Get(&count, tx.Rebind("SELECT COUNT(*) FROM document_revisions WHERE document = ?"), documentID); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return repository.appendRevision(tx, documentID, null.String{})
+}
+
+func readRevision(tx *sqlx.Tx, documentID models.ID, revision int64) (response models.DocumentRevision, err error) {
+	query := `
+	SELECT document, revision, name, body, language, tags, author, created_at
+	FROM document_revisions
+	WHERE document = ? AND revision = ?
+	`
+	if err := tx.Get(&response, tx.Rebind(query), documentID, revision); errors.Is(err, sql.ErrNoRows) {
+		return response, fmt.Errorf("%w: document with id '%d' has no revision %d", ErrRevisionNotFound, documentID, revision)
+	} else if err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// Returns wrapped ErrDocumentNotFound if document with passed id does not exist
+func checkDocumentExists(tx *sqlx.Tx, documentID models.ID) (err error) {
+	var count int
+	if err := tx.Get(&count, tx.Rebind("SELECT COUNT(*) FROM documents WHERE id = ? AND deleted_at IS NULL"), documentID); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: document with id '%d' does not exist", ErrDocumentNotFound, documentID)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func Test_Document_Revisions(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	tagRepository := repository.tagRepository.(*TagRepository)
+	first, _ := tagRepository.Create(models.CreateTagRequest{Name: "first"})
+	second, _ := tagRepository.Create(models.CreateTagRequest{Name: "second"})
+
+	createdDocument, err := repository.Create(models.CreateDocumentRequest{
+		Name:   "name",
+		Body:   "line 1\nline 2\n",
+		Tags:   []models.TagResponse{first},
+		Author: null.StringFrom("alice"),
+	})
+	require.NoError(t, err)
+
+	_, err = repository.Update(createdDocument.ID, models.UpdateDocumentRequest{
+		Name:         null.StringFrom("new name"),
+		Body:         null.StringFrom("line 1\nchanged line 2\n"),
+		TagsToAdd:    []models.TagResponse{second},
+		TagsToRemove: []models.TagResponse{first},
+	})
+	require.NoError(t, err)
+
+	revisions, err := repository.ListRevisions(createdDocument.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, int64(2), revisions[0].Revision, "latest revision must be first")
+	require.Equal(t, "new name", revisions[0].Name)
+	require.Equal(t, models.TagNames{"second"}, revisions[0].Tags)
+	require.False(t, revisions[0].Author.Valid)
+	require.Equal(t, int64(1), revisions[1].Revision)
+	require.Equal(t, "line 1\nline 2\n", revisions[1].Body)
+	require.Equal(t, models.TagNames{"first"}, revisions[1].Tags)
+	require.Equal(t, null.StringFrom("alice"), revisions[1].Author)
+
+	diff, err := revisions[1].Diff(revisions[0])
+	require.NoError(t, err)
+	require.Equal(t, "name", diff.NameFrom)
+	require.Equal(t, "new name", diff.NameTo)
+	require.Equal(t, []string{"second"}, diff.TagsAdded)
+	require.Equal(t, []string{"first"}, diff.TagsRemoved)
+	require.Contains(t, diff.Body, "-line 2\n+changed line 2\n")
+
+	_, err = repository.ReadRevision(createdDocument.ID, 3)
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+
+	_, err = repository.ListRevisions(createdDocument.ID + 1)
+	require.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func Test_Document_Restore_Revision(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	tagRepository := repository.tagRepository.(*TagRepository)
+	first, _ := tagRepository.Create(models.CreateTagRequest{Name: "first"})
+	second, _ := tagRepository.Create(models.CreateTagRequest{Name: "second"})
+	createdDocument, _ := repository.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{first}})
+	repository.Update(createdDocument.ID, models.UpdateDocumentRequest{
+		Body:         null.StringFrom("new body"),
+		TagsToAdd:    []models.TagResponse{second},
+		TagsToRemove: []models.TagResponse{first},
+	})
+
+	// Tag of first revision was merged so document gets merge target on restore
+	target, _ := tagRepository.Create(models.CreateTagRequest{Name: "target"})
+	_, err := tagRepository.Merge(target.ID, models.MergeTagsRequest{SourceIDs: []models.ID{first.ID}})
+	require.NoError(t, err)
+
+	restored, err := repository.Restore(createdDocument.ID, 1, null.StringFrom("bob"))
+	require.NoError(t, err)
+	require.Equal(t, "body", restored.Body)
+	require.Len(t, restored.Tags, 1)
+	require.Equal(t, target.ID, restored.Tags[0].ID)
+
+	revisions, err := repository.ListRevisions(createdDocument.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3, "restore must be saved as new revision")
+	require.Equal(t, "body", revisions[0].Body)
+	require.Equal(t, models.TagNames{"target"}, revisions[0].Tags)
+	require.Equal(t, null.StringFrom("bob"), revisions[0].Author)
+
+	_, err = repository.Restore(createdDocument.ID, 10, null.String{})
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func Test_Document_Revision_Of_Document_Without_Revisions(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	// Document created before revisions were introduced
+	result, err := repository.db.Exec("INSERT INTO documents (name, body, created_at, updated_at) VALUES ('name', 'body', ?, ?)", timestamp(), timestamp())
+	require.NoError(t, err)
+	id, _ := result.LastInsertId()
+
+	_, err = repository.Update(id, models.UpdateDocumentRequest{Body: null.StringFrom("new body")})
+	require.NoError(t, err)
+
+	revisions, err := repository.ListRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2, "state before first update must be preserved")
+	require.Equal(t, "body", revisions[1].Body)
+	require.Equal(t, "new body", revisions[0].Body)
+
+	_, err = repository.Update(id+1, models.UpdateDocumentRequest{Body: null.StringFrom("new body")})
+	require.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func Test_Document_Revision_Language(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	createdDocument, err := repository.Create(models.CreateDocumentRequest{
+		Name:     "name",
+		Body:     "body",
+		Language: models.LanguageEnglish,
+	})
+	require.NoError(t, err)
+
+	_, err = repository.Update(createdDocument.ID, models.UpdateDocumentRequest{
+		Language: null.StringFrom(models.LanguageRussian),
+	})
+	require.NoError(t, err)
+
+	revisions, err := repository.ListRevisions(createdDocument.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, models.LanguageRussian, revisions[0].Language)
+	require.Equal(t, models.LanguageEnglish, revisions[1].Language)
+
+	diff, err := revisions[1].Diff(revisions[0])
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageEnglish, diff.LanguageFrom)
+	require.Equal(t, models.LanguageRussian, diff.LanguageTo)
+
+	restored, err := repository.Restore(createdDocument.ID, 1, null.String{})
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageEnglish, restored.Language)
+}
@@ -1,12 +1,34 @@
 package repository
 
 import (
+	"cmp"
+	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v4"
 )
 
+var (
+	ErrTagParentNotFound = errors.New("parent tag not found")
+	ErrTagCycle          = errors.New("tag can not be a descendant of itself")
+)
+
+/*
+Common table expression which selects `id` of tag with given id and ids of all its descendants
+into `subtree` table. UNION is used instead of UNION ALL to stop recursion if there is a cycle in data.
+*/
+const tagSubtreeCTE = `
+	WITH RECURSIVE subtree(id) AS (
+		SELECT id FROM tags WHERE id = ?
+		UNION
+		SELECT tags.id FROM tags JOIN subtree ON tags.parent_id = subtree.id
+	)
+`
+
 type TagRepository struct {
 	db *sqlx.DB
 }
@@ -24,23 +46,37 @@ func (repository *TagRepository) Create(request models.CreateTagRequest) (respon
 	}
 	defer tx.Rollback()
 
-	res, err := tx.Exec("INSERT INTO tags VALUES (NULL, ?, ?)", request.Name, 0)
-	if err != nil {
+	// Creating tag with name of existing alias returns canonical tag
+	canonical, err := repository.readByAlias(tx, request.Name)
+	if err == nil {
+		return canonical, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
 		return response, err
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := repository.checkNameIsNotInTrash(tx, request.Name); err != nil {
 		return response, err
 	}
 
-	tagId, err := res.LastInsertId()
-	if err != nil {
+	if request.ParentID.Valid {
+		if err := repository.checkTagExists(tx, request.ParentID.Int64); err != nil {
+			return response, err
+		}
+	}
+
+	var tagId models.ID
+	if err := tx.Get(&tagId, tx.Rebind("INSERT INTO tags (name, assigned, parent_id) VALUES (?, ?, ?) RETURNING id"), request.Name, false, request.ParentID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return response, err
 	}
 
 	return models.TagResponse{
-		ID:   tagId,
-		Name: request.Name,
+		ID:       tagId,
+		Name:     request.Name,
+		ParentID: request.ParentID,
 	}, nil
 }
 
@@ -51,7 +87,7 @@ func (repository *TagRepository) Read(id models.ID) (response models.TagResponse
 	}
 	defer tx.Rollback()
 
-	if err := tx.Get(&response, "SELECT id, name, assigned FROM tags WHERE id = ?", id); err != nil {
+	if err := tx.Get(&response, tx.Rebind("SELECT id, name, assigned, parent_id FROM live_tags WHERE id = ?"), id); err != nil {
 		return response, err
 	}
 
@@ -67,7 +103,7 @@ func (repository *TagRepository) ReadMany(IDs []models.ID) (response []models.Ta
 		return response, nil
 	}
 
-	query, args, err := sqlx.In("SELECT id, name, assigned FROM tags WHERE id IN (?)", IDs)
+	query, args, err := sqlx.In("SELECT id, name, assigned, parent_id FROM live_tags WHERE id IN (?)", IDs)
 	if err != nil {
 		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
 	}
@@ -94,7 +130,7 @@ func (repository *TagRepository) ReadManyByNames(names []string) (response []mod
 		return response, nil
 	}
 
-	query, args, err := sqlx.In("SELECT id, name, assigned FROM tags WHERE name IN (?)", names)
+	query, args, err := sqlx.In("SELECT id, name, assigned, parent_id FROM live_tags WHERE name IN (?)", names)
 	if err != nil {
 		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
 	}
@@ -123,9 +159,32 @@ func (repository *TagRepository) Update(id models.ID, updateRequest models.Updat
 	}
 	defer tx.Rollback()
 
-	row := tx.QueryRowx("UPDATE tags SET name = ? WHERE id = ? RETURNING assigned", updateRequest.Name, id)
+	if err := repository.checkNameIsNotAlias(tx, updateRequest.Name); err != nil {
+		return response, err
+	}
+
+	if updateRequest.ParentID.Valid {
+		parentID := updateRequest.ParentID
+		if parentID.Int64 == 0 {
+			parentID = null.Int{}
+		} else if err := repository.checkParentAllowed(tx, id, parentID.Int64); err != nil {
+			return response, err
+		}
+
+		if _, err := tx.Exec(tx.Rebind("UPDATE tags SET parent_id = ? WHERE id = ?"), parentID, id); err != nil {
+			return response, err
+		}
+	}
+
+	row := tx.QueryRowx(tx.Rebind("UPDATE tags SET name = ? WHERE id = ? RETURNING assigned, parent_id"), updateRequest.Name, id)
 	var assigned bool
-	if err := row.Scan(&assigned); err != nil {
+	var parentID null.Int
+	if err := row.Scan(&assigned, &parentID); err != nil {
+		return response, err
+	}
+
+	// Descendants documents are reindexed too because their ancestors could be changed
+	if err := enqueueTagTreeIndexing(tx, id); err != nil {
 		return response, err
 	}
 
@@ -133,7 +192,35 @@ func (repository *TagRepository) Update(id models.ID, updateRequest models.Updat
 		return response, err
 	}
 
-	return models.TagResponse{ID: id, Name: updateRequest.Name, Assigned: assigned}, nil
+	return models.TagResponse{ID: id, Name: updateRequest.Name, Assigned: assigned, ParentID: parentID}, nil
+}
+
+// Returns wrapped ErrTagParentNotFound if tag with passed id does not exist
+func (repository *TagRepository) checkTagExists(tx *sqlx.Tx, id models.ID) (err error) {
+	var count int
+	if err := tx.Get(&count, tx.Rebind("SELECT COUNT(*) FROM tags WHERE id = ? AND deleted_at IS NULL"), id); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: tag with id '%d' does not exist", ErrTagParentNotFound, id)
+	}
+	return nil
+}
+
+// Checks that parent exists and that tag will not become an ancestor of itself if parent is set
+func (repository *TagRepository) checkParentAllowed(tx *sqlx.Tx, id models.ID, parentID models.ID) (err error) {
+	if err := repository.checkTagExists(tx, parentID); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.Get(&count, tx.Rebind(tagSubtreeCTE+"SELECT COUNT(*) FROM subtree WHERE id = ?"), id, parentID); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: tag '%d' is a descendant of tag '%d'", ErrTagCycle, parentID, id)
+	}
+	return nil
 }
 
 func (repository *TagRepository) Delete(id models.ID) (err error) {
@@ -143,7 +230,13 @@ func (repository *TagRepository) Delete(id models.ID) (err error) {
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec("DELETE FROM tags WHERE id = ?", id); err != nil {
+	// Children of deleted tag become roots so documents of whole subtree are reindexed
+	if err := enqueueTagTreeIndexing(tx, id); err != nil {
+		return err
+	}
+
+	// Tag is moved to trash. Its assignments, aliases and children are kept so it can be restored.
+	if _, err := tx.Exec(tx.Rebind("UPDATE tags SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"), timestamp(), id); err != nil {
 		return err
 	}
 
@@ -161,7 +254,7 @@ func (repository *TagRepository) List() (response []models.TagResponse, err erro
 	}
 	defer tx.Rollback()
 
-	if err := tx.Select(&response, "SELECT id, name, assigned FROM tags"); err != nil {
+	if err := tx.Select(&response, tx.Rebind("SELECT id, name, assigned, parent_id FROM live_tags")); err != nil {
 		return response, err
 	}
 
@@ -172,13 +265,46 @@ func (repository *TagRepository) List() (response []models.TagResponse, err erro
 	return response, nil
 }
 
+// Returns all tags as forest where every root is a tag without parent
+func (repository *TagRepository) Tree() (response []models.TagTreeNode, err error) {
+	tags, err := repository.List()
+	if err != nil {
+		return response, err
+	}
+
+	childrenByParent := make(map[models.ID][]models.TagResponse, len(tags))
+	roots := []models.TagResponse{}
+	for _, tag := range tags {
+		if tag.ParentID.Valid {
+			childrenByParent[tag.ParentID.Int64] = append(childrenByParent[tag.ParentID.Int64], tag)
+		} else {
+			roots = append(roots, tag)
+		}
+	}
+
+	var buildNodes func(tags []models.TagResponse) []models.TagTreeNode
+	buildNodes = func(tags []models.TagResponse) []models.TagTreeNode {
+		nodes := make([]models.TagTreeNode, 0, len(tags))
+		for _, tag := range tags {
+			nodes = append(nodes, models.TagTreeNode{
+				TagResponse: tag,
+				Children:    buildNodes(childrenByParent[tag.ID]),
+			})
+		}
+		return nodes
+	}
+
+	return buildNodes(roots), nil
+}
+
 func (repository *TagRepository) ListForDocument(tx *sqlx.Tx, documentID models.ID) (response []models.TagResponse, err error) {
 	query := `
-	SELECT id, name, assigned FROM tags
+	SELECT id, name, assigned, parent_id FROM live_tags
 	WHERE id IN (
 		SELECT tag FROM tags_documents
 		WHERE document = ?
 	)
+	ORDER BY id
 		`
 
 	if tx == nil {
@@ -189,7 +315,7 @@ func (repository *TagRepository) ListForDocument(tx *sqlx.Tx, documentID models.
 		defer tx.Rollback()
 	}
 
-	if err := tx.Select(&response, query, documentID); err != nil {
+	if err := tx.Select(&response, tx.Rebind(query), documentID); err != nil {
 		return response, err
 	}
 
@@ -202,6 +328,90 @@ func (repository *TagRepository) ListForDocument(tx *sqlx.Tx, documentID models.
 	return response, nil
 }
 
+// Maximum number of ids bound to one query of batched loader, keeps queries below bound parameters limit of databases
+const tagLoaderBatchSize = 1000
+
+/*
+Returns tags of passed documents by document id, tags of every document are ordered by id.
+Links between documents and tags are loaded first and then distinct tags by their ids, with one query per tagLoaderBatchSize ids each,
+instead of query per document. Tags are not joined to links because SQLite then may choose to scan links by tag. Documents without tags are absent in returned map.
+*/
+func (repository *TagRepository) ListForDocuments(tx *sqlx.Tx, documentIDs []models.ID) (response map[models.ID][]models.TagResponse, err error) {
+	response = make(map[models.ID][]models.TagResponse, len(documentIDs))
+	if len(documentIDs) == 0 {
+		return response, nil
+	}
+
+	if tx == nil {
+		tx, err = repository.db.Beginx()
+		if err != nil {
+			return response, ErrTransactionOpen
+		}
+		defer tx.Rollback()
+	}
+
+	type documentTag struct {
+		DocumentID models.ID `db:"document"`
+		TagID      models.ID `db:"tag"`
+	}
+	var links []documentTag
+	err = inBatches(documentIDs, func(batch []models.ID) error {
+		var batchLinks []documentTag
+		if err := selectIn(tx, &batchLinks, true, "SELECT document, tag FROM tags_documents WHERE document IN (?)", batch); err != nil {
+			return err
+		}
+		links = append(links, batchLinks...)
+		return nil
+	})
+	if err != nil {
+		return response, err
+	}
+
+	tagIDs := make([]models.ID, 0, len(links))
+	for _, link := range links {
+		tagIDs = append(tagIDs, link.TagID)
+	}
+	slices.Sort(tagIDs)
+	tagIDs = slices.Compact(tagIDs)
+
+	tagsByID := make(map[models.ID]models.TagResponse, len(tagIDs))
+	err = inBatches(tagIDs, func(batch []models.ID) error {
+		var tags []models.TagResponse
+		if err := selectIn(tx, &tags, true, "SELECT id, name, assigned, parent_id FROM live_tags WHERE id IN (?)", batch); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			tagsByID[tag.ID] = tag
+		}
+		return nil
+	})
+	if err != nil {
+		return response, err
+	}
+
+	// Links of tags in trash are kept, such tags are absent among loaded ones
+	for _, link := range links {
+		if tag, ok := tagsByID[link.TagID]; ok {
+			response[link.DocumentID] = append(response[link.DocumentID], tag)
+		}
+	}
+	for _, tags := range response {
+		slices.SortFunc(tags, func(a, b models.TagResponse) int { return cmp.Compare(a.ID, b.ID) })
+	}
+
+	return response, nil
+}
+
+// Calls function for consecutive parts of ids with at most tagLoaderBatchSize ids each
+func inBatches(IDs []models.ID, function func(batch []models.ID) error) error {
+	for start := 0; start < len(IDs); start += tagLoaderBatchSize {
+		if err := function(IDs[start:min(start+tagLoaderBatchSize, len(IDs))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (repository *TagRepository) AssignForDocument(tx *sqlx.Tx, documentID models.ID, tags []models.TagResponse) (err error) {
 	if tx == nil {
 		tx, err = repository.db.Beginx()
@@ -213,7 +423,7 @@ func (repository *TagRepository) AssignForDocument(tx *sqlx.Tx, documentID model
 
 	// TODO: IN query to avoid loop
 	for _, tag := range tags {
-		_, err := tx.Exec("INSERT INTO tags_documents VALUES (?, ?)", tag.ID, documentID)
+		_, err := tx.Exec(tx.Rebind("INSERT INTO tags_documents VALUES (?, ?)"), tag.ID, documentID)
 		if err != nil {
 			return err
 		}
@@ -241,7 +451,7 @@ func (repository *TagRepository) DeleteForDocument(tx *sqlx.Tx, documentID model
 
 	// TODO: IN query to avoid loop
 	for _, tag := range tags {
-		_, err := tx.Exec("DELETE FROM tags_documents WHERE tag = ? AND document = ?", tag.ID, documentID)
+		_, err := tx.Exec(tx.Rebind("DELETE FROM tags_documents WHERE tag = ? AND document = ?"), tag.ID, documentID)
 		if err != nil {
 			return err
 		}
@@ -265,12 +475,12 @@ are attached or detached from it.
 */
 func (repository *TagRepository) toggleTagAssigned(tx *sqlx.Tx, tagID models.ID) (err error) {
 	query := `
-	UPDATE tags SET 
-	assigned = CASE
-		           WHEN (SELECT COUNT(*) FROM tags_documents WHERE tag = ?) = 0
-				       THEN 0
-				   ELSE 1
-			   END
+	UPDATE tags SET
+	assigned = EXISTS (
+		SELECT 1 FROM tags_documents
+		JOIN documents ON documents.id = tags_documents.document
+		WHERE tags_documents.tag = ? AND documents.deleted_at IS NULL
+	)
 	WHERE id = ?
 `
 	if tx == nil {
@@ -281,7 +491,7 @@ func (repository *TagRepository) toggleTagAssigned(tx *sqlx.Tx, tagID models.ID)
 		defer tx.Rollback()
 	}
 
-	if _, err := tx.Exec(query, tagID, tagID); err != nil {
+	if _, err := tx.Exec(tx.Rebind(query), tagID, tagID); err != nil {
 		return err
 	}
 
@@ -293,3 +503,21 @@ func (repository *TagRepository) toggleTagAssigned(tx *sqlx.Tx, tagID models.ID)
 
 	return err
 }
+
+// Sets `assigned` of every tag of document according to documents which are not in trash
+func toggleDocumentTagsAssigned(tx *sqlx.Tx, documentID models.ID) (err error) {
+	query := `
+	UPDATE tags SET
+	assigned = EXISTS (
+		SELECT 1 FROM tags_documents
+		JOIN documents ON documents.id = tags_documents.document
+		WHERE tags_documents.tag = tags.id AND documents.deleted_at IS NULL
+	)
+	WHERE id IN (
+		SELECT tag FROM tags_documents
+		WHERE document = ?
+	)
+	`
+	_, err = tx.Exec(tx.Rebind(query), documentID)
+	return err
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrTagNameTaken     = errors.New("name is already used by tag or tag alias")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagAliasNotFound = errors.New("tag alias not found")
+)
+
+func (repository *TagRepository) ReadWithAliases(id models.ID) (response models.TagWithAliasesResponse, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if err := tx.Get(&response.TagResponse, tx.Rebind("SELECT id, name, assigned, parent_id FROM live_tags WHERE id = ?"), id); err != nil {
+		return response, err
+	}
+
+	if response.Aliases, err = repository.listAliases(tx, id); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Adds alias to tag. Alias must not be equal to any tag name or other alias.
+func (repository *TagRepository) CreateAlias(tagID models.ID, request models.CreateTagAliasRequest) (response models.TagWithAliasesResponse, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if err := tx.Get(&response.TagResponse, tx.Rebind("SELECT id, name, assigned, parent_id FROM live_tags WHERE id = ?"), tagID); errors.Is(err, sql.ErrNoRows) {
+		return response, fmt.Errorf("%w: tag with id '%d' does not exist", ErrTagNotFound, tagID)
+	} else if err != nil {
+		return response, err
+	}
+
+	if err := repository.checkNameIsFree(tx, request.Name); err != nil {
+		return response, err
+	}
+
+	if _, err := tx.Exec(tx.Rebind("INSERT INTO tag_aliases (tag, name) VALUES (?, ?)"), tagID, request.Name); err != nil {
+		return response, err
+	}
+
+	if response.Aliases, err = repository.listAliases(tx, tagID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Removes alias of tag. Returns wrapped ErrTagAliasNotFound if tag does not exist or has no such alias.
+func (repository *TagRepository) DeleteAlias(tagID models.ID, alias string) (err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(tx.Rebind("DELETE FROM tag_aliases WHERE tag IN (SELECT id FROM live_tags WHERE id = ?) AND name = ?"), tagID, alias)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("%w: tag with id '%d' has no alias '%s'", ErrTagAliasNotFound, tagID, alias)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns canonical tags for passed names which are aliases. Names which are not aliases are not included in result.
+func (repository *TagRepository) ResolveAliases(names []string) (response map[string]models.TagResponse, err error) {
+	response = make(map[string]models.TagResponse)
+	if len(names) == 0 {
+		return response, nil
+	}
+
+	query, args, err := sqlx.In(`
+	SELECT tag_aliases.name AS alias, live_tags.id, live_tags.name, live_tags.assigned, live_tags.parent_id
+	FROM tag_aliases
+	JOIN live_tags ON live_tags.id = tag_aliases.tag
+	WHERE tag_aliases.name IN (?)
+	`, names)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	var rows []struct {
+		Alias string `db:"alias"`
+		models.TagResponse
+	}
+	if err := tx.Select(&rows, tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	for _, row := range rows {
+		response[row.Alias] = row.TagResponse
+	}
+
+	return response, nil
+}
+
+/*
+Returns canonical tags for passed tags. Tags with non-zero ID are returned as is,
+other tags are looked up by name which may be a tag name or an alias.
+Result contains every tag once even if it was passed several times under different names.
+*/
+func (repository *TagRepository) Resolve(tx *sqlx.Tx, tags []models.TagResponse) (response []models.TagResponse, err error) {
+	ownTransaction := tx == nil
+	if ownTransaction {
+		tx, err = repository.db.Beginx()
+		if err != nil {
+			return response, ErrTransactionOpen
+		}
+		defer tx.Rollback()
+	}
+
+	seen := make(map[models.ID]struct{}, len(tags))
+	for _, tag := range tags {
+		resolved := tag
+		if tag.ID == 0 {
+			resolved, err = repository.readByNameOrAlias(tx, tag.Name)
+			if errors.Is(err, sql.ErrNoRows) {
+				return response, fmt.Errorf("%w: tag with name '%s' does not exist", ErrTagNotFound, tag.Name)
+			} else if err != nil {
+				return response, err
+			}
+		}
+
+		if _, ok := seen[resolved.ID]; ok {
+			continue
+		}
+		seen[resolved.ID] = struct{}{}
+		response = append(response, resolved)
+	}
+
+	if ownTransaction {
+		if err := tx.Commit(); err != nil {
+			return response, err
+		}
+	}
+
+	return response, nil
+}
+
+func (repository *TagRepository) readByNameOrAlias(tx *sqlx.Tx, name string) (response models.TagResponse, err error) {
+	query := `
+	SELECT id, name, assigned, parent_id FROM live_tags
+	WHERE name = ? OR id IN (
+		SELECT tag FROM tag_aliases
+		WHERE name = ?
+	)
+	`
+	err = tx.Get(&response, tx.Rebind(query), name, name)
+	return response, err
+}
+
+func (repository *TagRepository) readByAlias(tx *sqlx.Tx, alias string) (response models.TagResponse, err error) {
+	query := `
+	SELECT id, name, assigned, parent_id FROM live_tags
+	WHERE id IN (
+		SELECT tag FROM tag_aliases
+		WHERE name = ?
+	)
+	`
+	err = tx.Get(&response, tx.Rebind(query), alias)
+	return response, err
+}
+
+func (repository *TagRepository) listAliases(tx *sqlx.Tx, tagID models.ID) (response []string, err error) {
+	response = []string{}
+	if err := tx.Select(&response, tx.Rebind("SELECT name FROM tag_aliases WHERE tag = ? ORDER BY name"), tagID); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// Returns wrapped ErrTagNameTaken if name is used by any tag or alias
+func (repository *TagRepository) checkNameIsFree(tx *sqlx.Tx, name string) (err error) {
+	var count int
+	query := `
+	SELECT
+		(SELECT COUNT(*) FROM tags WHERE name = ?) +
+		(SELECT COUNT(*) FROM tag_aliases WHERE name = ?)
+	`
+	if err := tx.Get(&count, tx.Rebind(query), name, name); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: '%s'", ErrTagNameTaken, name)
+	}
+	return nil
+}
+
+// Returns wrapped ErrTagNameTaken if name is used by tag in trash
+func (repository *TagRepository) checkNameIsNotInTrash(tx *sqlx.Tx, name string) (err error) {
+	var count int
+	if err := tx.Get(&count, tx.Rebind("SELECT COUNT(*) FROM tags WHERE name = ? AND deleted_at IS NOT NULL"), name); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: '%s' is a name of tag in trash, restore it instead", ErrTagNameTaken, name)
+	}
+	return nil
+}
+
+// Returns wrapped ErrTagNameTaken if name is used by any alias
+func (repository *TagRepository) checkNameIsNotAlias(tx *sqlx.Tx, name string) (err error) {
+	var count int
+	if err := tx.Get(&count, tx.Rebind("SELECT COUNT(*) FROM tag_aliases WHERE name = ?"), name); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: '%s' is an alias", ErrTagNameTaken, name)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v4"
+)
+
+var (
+	ErrTagMergeIntoItself = errors.New("tag can not be merged into itself")
+)
+
+/*
+Common table expression which selects ids of all ancestors of tag with given id into `ancestors` table.
+*/
+const tagAncestorsCTE = `
+	WITH RECURSIVE ancestors(id) AS (
+		SELECT parent_id FROM tags WHERE id = ? AND parent_id IS NOT NULL
+		UNION
+		SELECT tags.parent_id FROM tags JOIN ancestors ON tags.id = ancestors.id WHERE tags.parent_id IS NOT NULL
+	)
+`
+
+/*
+Folds source tags into target tag in single transaction:
+  - documents of source tags are assigned to target tag (documents which already have target are skipped)
+  - children of source tags become children of target tag unless they are target or its ancestors,
+    which are moved to the nearest ancestor that is not a source
+  - source tags names and their aliases become aliases of target tag
+  - source tags are deleted
+
+Documents of source tags subtrees are added to index outbox.
+*/
+func (repository *TagRepository) Merge(targetID models.ID, request models.MergeTagsRequest) (response models.TagWithAliasesResponse, err error) {
+	sourceIDs := slices.Clone(request.SourceIDs)
+	slices.Sort(sourceIDs)
+	sourceIDs = slices.Compact(sourceIDs)
+	if slices.Contains(sourceIDs, targetID) {
+		return response, fmt.Errorf("%w: tag '%d' is in sources", ErrTagMergeIntoItself, targetID)
+	}
+
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	if err := repository.checkTagsExist(tx, append([]models.ID{targetID}, sourceIDs...)); err != nil {
+		return response, err
+	}
+
+	// Documents of whole source subtrees are reindexed because ancestors of their tags change
+	for _, sourceID := range sourceIDs {
+		if err := enqueueTagTreeIndexing(tx, sourceID); err != nil {
+			return response, err
+		}
+	}
+
+	query, args, err := sqlx.In(`
+	INSERT INTO tags_documents (tag, document)
+	SELECT DISTINCT CAST(? AS BIGINT), document FROM tags_documents
+	WHERE tag IN (?) AND document NOT IN (
+		SELECT document FROM tags_documents
+		WHERE tag = ?
+	)
+	`, targetID, sourceIDs, targetID)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	if err := liftOutOfSources(tx, targetID, sourceIDs); err != nil {
+		return response, err
+	}
+
+	query, args, err = sqlx.In(tagAncestorsCTE+`
+	UPDATE tags SET parent_id = ?
+	WHERE parent_id IN (?) AND id <> ? AND id NOT IN (SELECT id FROM ancestors)
+	`, targetID, targetID, sourceIDs, targetID)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	query, args, err = sqlx.In("SELECT name FROM tags WHERE id IN (?)", sourceIDs)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	var sourceNames []string
+	if err := tx.Select(&sourceNames, tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	query, args, err = sqlx.In("UPDATE tag_aliases SET tag = ? WHERE tag IN (?)", targetID, sourceIDs)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	query, args, err = sqlx.In("DELETE FROM tags WHERE id IN (?)", sourceIDs)
+	if err != nil {
+		return response, fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return response, err
+	}
+
+	// Old names keep resolving to merged tag
+	for _, name := range sourceNames {
+		if _, err := tx.Exec(tx.Rebind("INSERT INTO tag_aliases (tag, name) VALUES (?, ?)"), targetID, name); err != nil {
+			return response, err
+		}
+	}
+
+	if err := repository.toggleTagAssigned(tx, targetID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Get(&response.TagResponse, tx.Rebind("SELECT id, name, assigned, parent_id FROM live_tags WHERE id = ?"), targetID); err != nil {
+		return response, err
+	}
+
+	if response.Aliases, err = repository.listAliases(tx, targetID); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+/*
+Moves target and its ancestors whose parents are sources to the nearest ancestor that is not a source,
+so they stay in hierarchy after sources are deleted. Tag becomes root if all of its ancestors are sources.
+*/
+func liftOutOfSources(tx *sqlx.Tx, targetID models.ID, sourceIDs []models.ID) error {
+	path := []models.ID{targetID}
+	for {
+		var parentID null.Int
+		if err := tx.Get(&parentID, tx.Rebind("SELECT parent_id FROM tags WHERE id = ?"), path[len(path)-1]); err != nil {
+			return err
+		}
+		if !parentID.Valid || slices.Contains(path, parentID.Int64) {
+			break
+		}
+		path = append(path, parentID.Int64)
+	}
+
+	for i := 0; i < len(path)-1; i++ {
+		if slices.Contains(sourceIDs, path[i]) || !slices.Contains(sourceIDs, path[i+1]) {
+			continue
+		}
+
+		newParentID := null.Int{}
+		for _, ancestorID := range path[i+1:] {
+			if !slices.Contains(sourceIDs, ancestorID) {
+				newParentID = null.IntFrom(ancestorID)
+				break
+			}
+		}
+		if _, err := tx.Exec(tx.Rebind("UPDATE tags SET parent_id = ? WHERE id = ?"), newParentID, path[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns wrapped ErrTagNotFound if any of passed tags does not exist
+func (repository *TagRepository) checkTagsExist(tx *sqlx.Tx, IDs []models.ID) (err error) {
+	query, args, err := sqlx.In("SELECT id FROM tags WHERE id IN (?) AND deleted_at IS NULL", IDs)
+	if err != nil {
+		return fmt.Errorf("unable to rebind query for slice usage in sqlx.In: %w", err)
+	}
+
+	var existingIDs []models.ID
+	if err := tx.Select(&existingIDs, tx.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	for _, id := range IDs {
+		if !slices.Contains(existingIDs, id) {
+			return fmt.Errorf("%w: tag with id '%d' does not exist", ErrTagNotFound, id)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
 	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
 )
 
 func newTestTagRepository() (testRepository *TagRepository, cleanupFunc func()) {
@@ -147,3 +150,282 @@ func Test_List_Tags(t *testing.T) {
 		)
 	}
 }
+
+func Test_Create_Tag_With_Parent(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	parent, _ := repository.Create(models.CreateTagRequest{Name: "sport"})
+
+	actual, err := repository.Create(models.CreateTagRequest{
+		Name:     "football",
+		ParentID: null.IntFrom(parent.ID),
+	})
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(parent.ID), actual.ParentID)
+
+	_, err = repository.Create(models.CreateTagRequest{
+		Name:     "hockey",
+		ParentID: null.IntFrom(parent.ID + 100),
+	})
+	require.ErrorIs(t, err, ErrTagParentNotFound)
+}
+
+func Test_Update_Tag_Parent(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	sport, _ := repository.Create(models.CreateTagRequest{Name: "sport"})
+	football, _ := repository.Create(models.CreateTagRequest{Name: "football", ParentID: null.IntFrom(sport.ID)})
+	premierLeague, _ := repository.Create(models.CreateTagRequest{Name: "premier league", ParentID: null.IntFrom(football.ID)})
+
+	_, err := repository.Update(sport.ID, models.UpdateTagRequest{Name: sport.Name, ParentID: null.IntFrom(premierLeague.ID)})
+	require.ErrorIs(t, err, ErrTagCycle)
+
+	_, err = repository.Update(sport.ID, models.UpdateTagRequest{Name: sport.Name, ParentID: null.IntFrom(sport.ID)})
+	require.ErrorIs(t, err, ErrTagCycle)
+
+	actual, err := repository.Update(premierLeague.ID, models.UpdateTagRequest{Name: premierLeague.Name})
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(football.ID), actual.ParentID, "parent must be kept if it is not passed")
+
+	actual, err = repository.Update(premierLeague.ID, models.UpdateTagRequest{Name: premierLeague.Name, ParentID: null.IntFrom(sport.ID)})
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(sport.ID), actual.ParentID)
+
+	actual, err = repository.Update(premierLeague.ID, models.UpdateTagRequest{Name: premierLeague.Name, ParentID: null.IntFrom(0)})
+	require.NoError(t, err)
+	require.False(t, actual.ParentID.Valid)
+}
+
+func Test_Tree_Tags(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	sport, _ := repository.Create(models.CreateTagRequest{Name: "sport"})
+	football, _ := repository.Create(models.CreateTagRequest{Name: "football", ParentID: null.IntFrom(sport.ID)})
+	premierLeague, _ := repository.Create(models.CreateTagRequest{Name: "premier league", ParentID: null.IntFrom(football.ID)})
+	politics, _ := repository.Create(models.CreateTagRequest{Name: "politics"})
+
+	expected := []models.TagTreeNode{
+		{
+			TagResponse: sport,
+			Children: []models.TagTreeNode{
+				{
+					TagResponse: football,
+					Children: []models.TagTreeNode{
+						{TagResponse: premierLeague, Children: []models.TagTreeNode{}},
+					},
+				},
+			},
+		},
+		{TagResponse: politics, Children: []models.TagTreeNode{}},
+	}
+
+	actual, err := repository.Tree()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	// Children of deleted tag become roots
+	require.NoError(t, repository.Delete(sport.ID))
+	football.ParentID = null.Int{}
+	expected = []models.TagTreeNode{
+		{
+			TagResponse: football,
+			Children: []models.TagTreeNode{
+				{TagResponse: premierLeague, Children: []models.TagTreeNode{}},
+			},
+		},
+		{TagResponse: politics, Children: []models.TagTreeNode{}},
+	}
+
+	actual, err = repository.Tree()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func Test_Create_Tag_Alias(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	machineLearning, _ := repository.Create(models.CreateTagRequest{Name: "Machine Learning"})
+	repository.Create(models.CreateTagRequest{Name: "AI"})
+
+	actual, err := repository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "ML"})
+	require.NoError(t, err)
+	require.Equal(t, models.TagWithAliasesResponse{TagResponse: machineLearning, Aliases: []string{"ML"}}, actual)
+
+	actual, err = repository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "machine-learning"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ML", "machine-learning"}, actual.Aliases)
+
+	_, err = repository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "AI"})
+	require.ErrorIs(t, err, ErrTagNameTaken, "alias must not be equal to tag name")
+
+	_, err = repository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "ML"})
+	require.ErrorIs(t, err, ErrTagNameTaken, "alias must be unique")
+
+	_, err = repository.CreateAlias(machineLearning.ID+100, models.CreateTagAliasRequest{Name: "DL"})
+	require.ErrorIs(t, err, ErrTagNotFound)
+
+	readTag, err := repository.ReadWithAliases(machineLearning.ID)
+	require.NoError(t, err)
+	require.Equal(t, actual, readTag)
+}
+
+func Test_Resolve_Tag_Alias(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	machineLearning, _ := repository.Create(models.CreateTagRequest{Name: "Machine Learning"})
+	ai, _ := repository.Create(models.CreateTagRequest{Name: "AI"})
+	repository.CreateAlias(machineLearning.ID, models.CreateTagAliasRequest{Name: "ML"})
+
+	actual, err := repository.Create(models.CreateTagRequest{Name: "ML"})
+	require.NoError(t, err)
+	require.Equal(t, machineLearning, actual, "creating tag with alias name must return canonical tag")
+
+	_, err = repository.Update(ai.ID, models.UpdateTagRequest{Name: "ML"})
+	require.ErrorIs(t, err, ErrTagNameTaken, "tag must not be renamed to alias")
+
+	aliases, err := repository.ResolveAliases([]string{"ML", "AI", "unknown"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]models.TagResponse{"ML": machineLearning}, aliases)
+
+	resolved, err := repository.Resolve(nil, []models.TagResponse{{Name: "ML"}, {ID: machineLearning.ID}, {Name: "AI"}})
+	require.NoError(t, err)
+	require.Equal(t, []models.TagResponse{machineLearning, ai}, resolved)
+
+	_, err = repository.Resolve(nil, []models.TagResponse{{Name: "unknown"}})
+	require.ErrorIs(t, err, ErrTagNotFound)
+
+	require.ErrorIs(t, repository.DeleteAlias(ai.ID, "ML"), ErrTagAliasNotFound, "alias of other tag must not be deleted")
+	require.ErrorIs(t, repository.DeleteAlias(999, "ML"), ErrTagAliasNotFound)
+
+	require.NoError(t, repository.DeleteAlias(machineLearning.ID, "ML"))
+	aliases, err = repository.ResolveAliases([]string{"ML"})
+	require.NoError(t, err)
+	require.Empty(t, aliases)
+
+	require.ErrorIs(t, repository.DeleteAlias(machineLearning.ID, "ML"), ErrTagAliasNotFound)
+}
+
+func Test_Merge_Tags(t *testing.T) {
+	documentRepository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+
+	repository := documentRepository.tagRepository.(*TagRepository)
+	machineLearning, _ := repository.Create(models.CreateTagRequest{Name: "Machine Learning"})
+	ml, _ := repository.Create(models.CreateTagRequest{Name: "ML"})
+	deepLearning, _ := repository.Create(models.CreateTagRequest{Name: "Deep Learning", ParentID: null.IntFrom(ml.ID)})
+	repository.CreateAlias(ml.ID, models.CreateTagAliasRequest{Name: "ml"})
+
+	bothDocument, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "both", Body: "body", Tags: []models.TagResponse{machineLearning, ml}})
+	sourceDocument, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "source", Body: "body", Tags: []models.TagResponse{ml}})
+	childDocument, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "child", Body: "body", Tags: []models.TagResponse{deepLearning}})
+	documentRepository.Create(models.CreateDocumentRequest{Name: "target", Body: "body", Tags: []models.TagResponse{machineLearning}})
+
+	_, err := repository.Merge(machineLearning.ID, models.MergeTagsRequest{SourceIDs: []models.ID{machineLearning.ID}})
+	require.ErrorIs(t, err, ErrTagMergeIntoItself)
+
+	_, err = repository.Merge(machineLearning.ID, models.MergeTagsRequest{SourceIDs: []models.ID{ml.ID + 100}})
+	require.ErrorIs(t, err, ErrTagNotFound)
+
+	outboxRepository := NewOutboxRepository(repository.db)
+	entries, _ := outboxRepository.Fetch(100, time.Now())
+	outboxRepository.Complete(entries)
+
+	actual, err := repository.Merge(machineLearning.ID, models.MergeTagsRequest{SourceIDs: []models.ID{ml.ID, ml.ID}})
+	require.NoError(t, err)
+	require.Equal(t, machineLearning.ID, actual.ID)
+	require.True(t, actual.Assigned)
+	require.Equal(t, []string{"ML", "ml"}, actual.Aliases, "source name and aliases must become aliases of target")
+
+	entries, err = outboxRepository.Fetch(100, time.Now())
+	require.NoError(t, err)
+	affectedDocumentIDs := make([]models.ID, 0, len(entries))
+	for _, entry := range entries {
+		affectedDocumentIDs = append(affectedDocumentIDs, entry.DocumentID)
+	}
+	require.ElementsMatch(t, []models.ID{bothDocument.ID, sourceDocument.ID, childDocument.ID}, affectedDocumentIDs, "documents of source subtree must be reindexed")
+
+	_, err = repository.Read(ml.ID)
+	require.Error(t, err, "source tag must be deleted")
+
+	child, err := repository.Read(deepLearning.ID)
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(machineLearning.ID), child.ParentID, "children of source must be moved to target")
+
+	documents, err := documentRepository.ReadMany([]models.ID{bothDocument.ID, sourceDocument.ID})
+	require.NoError(t, err)
+	for _, document := range documents {
+		require.Len(t, document.Tags, 1, "assignments must be deduplicated")
+		require.Equal(t, machineLearning.ID, document.Tags[0].ID)
+	}
+}
+
+func Test_Merge_Tags_Target_Child_Of_Source(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+
+	root, _ := repository.Create(models.CreateTagRequest{Name: "root"})
+	source, _ := repository.Create(models.CreateTagRequest{Name: "source", ParentID: null.IntFrom(root.ID)})
+	target, _ := repository.Create(models.CreateTagRequest{Name: "target", ParentID: null.IntFrom(source.ID)})
+	sibling, _ := repository.Create(models.CreateTagRequest{Name: "sibling", ParentID: null.IntFrom(source.ID)})
+
+	actual, err := repository.Merge(target.ID, models.MergeTagsRequest{SourceIDs: []models.ID{source.ID}})
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(root.ID), actual.ParentID, "target must be moved to parent of source")
+
+	tree, err := repository.Tree()
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	require.Equal(t, root.ID, tree[0].ID)
+	require.Len(t, tree[0].Children, 1)
+	require.Equal(t, target.ID, tree[0].Children[0].ID)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	require.Equal(t, sibling.ID, tree[0].Children[0].Children[0].ID, "other children of source must be moved to target")
+
+	// Target becomes root if all of its ancestors are sources
+	parent, _ := repository.Create(models.CreateTagRequest{Name: "parent"})
+	child, _ := repository.Create(models.CreateTagRequest{Name: "child", ParentID: null.IntFrom(parent.ID)})
+	grandchild, _ := repository.Create(models.CreateTagRequest{Name: "grandchild", ParentID: null.IntFrom(child.ID)})
+
+	actual, err = repository.Merge(grandchild.ID, models.MergeTagsRequest{SourceIDs: []models.ID{parent.ID, child.ID}})
+	require.NoError(t, err)
+	require.False(t, actual.ParentID.Valid)
+}
+
+func Test_ListForDocuments(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+	documentRepository := NewDocumentRepository(repository.db, repository)
+
+	first, _ := repository.Create(models.CreateTagRequest{Name: "first"})
+	second, _ := repository.Create(models.CreateTagRequest{Name: "second"})
+	trashed, _ := repository.Create(models.CreateTagRequest{Name: "trashed"})
+
+	var documentIDs []models.ID
+	for i := 0; i < tagLoaderBatchSize+5; i++ {
+		tags := []models.TagResponse{second, first}
+		if i%2 == 1 {
+			tags = []models.TagResponse{trashed}
+		}
+		document, err := documentRepository.Create(models.CreateDocumentRequest{Name: fmt.Sprint(i), Body: "body", Tags: tags})
+		require.NoError(t, err)
+		documentIDs = append(documentIDs, document.ID)
+	}
+	require.NoError(t, repository.Delete(trashed.ID))
+
+	tagsByDocument, err := repository.ListForDocuments(nil, documentIDs)
+	require.NoError(t, err)
+	require.Len(t, tagsByDocument, (len(documentIDs)+1)/2, "documents with tags in trash only have no tags")
+
+	first.Assigned, second.Assigned = true, true
+	for i, id := range documentIDs {
+		if i%2 == 1 {
+			continue
+		}
+		require.Equal(t, []models.TagResponse{first, second}, tagsByDocument[id], "tags of document %d", id)
+	}
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotInTrash = errors.New("item is not in trash")
+)
+
+/*
+Trash keeps documents and tags deleted by DocumentRepository.Delete and TagRepository.Delete.
+Items in trash are hidden from every other repository method and from search index
+until they are restored or permanently deleted by Purge.
+*/
+type TrashRepository struct {
+	db *sqlx.DB
+}
+
+func NewTrashRepository(db *sqlx.DB) *TrashRepository {
+	return &TrashRepository{
+		db: db,
+	}
+}
+
+func (repository *TrashRepository) List() (response models.TrashResponse, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	response.Documents = []models.TrashedDocument{}
+	query := `
+	SELECT id, name, body, language, created_at, updated_at, deleted_at
+	FROM documents
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC
+	`
+	if err := tx.Select(&response.Documents, tx.Rebind(query)); err != nil {
+		return response, err
+	}
+
+	response.Tags = []models.TrashedTag{}
+	query = `
+	SELECT id, name, assigned, parent_id, deleted_at
+	FROM tags
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC
+	`
+	if err := tx.Select(&response.Tags, tx.Rebind(query)); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Takes document out of trash. Tags which were assigned to document before deletion are assigned again.
+func (repository *TrashRepository) RestoreDocument(id models.ID) (err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(tx.Rebind("UPDATE documents SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"), id)
+	if err != nil {
+		return err
+	}
+	if restored, err := result.RowsAffected(); err != nil {
+		return err
+	} else if restored == 0 {
+		return fmt.Errorf("%w: document with id '%d'", ErrNotInTrash, id)
+	}
+
+	if err := toggleDocumentTagsAssigned(tx, id); err != nil {
+		return err
+	}
+
+	if err := enqueueIndexing(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Takes tag out of trash together with its assignments, aliases and children
+func (repository *TrashRepository) RestoreTag(id models.ID) (err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(tx.Rebind("UPDATE tags SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"), id)
+	if err != nil {
+		return err
+	}
+	if restored, err := result.RowsAffected(); err != nil {
+		return err
+	} else if restored == 0 {
+		return fmt.Errorf("%w: tag with id '%d'", ErrNotInTrash, id)
+	}
+
+	// Children of restored tag get it as ancestor again so documents of whole subtree are reindexed
+	if err := enqueueTagTreeIndexing(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+Permanently deletes documents and tags which are in trash since passed time or earlier.
+Children of purged tags become roots. Purged items are already absent from search index so it is not changed.
+*/
+func (repository *TrashRepository) Purge(before time.Time) (response models.PurgeResult, err error) {
+	tx, err := repository.db.Beginx()
+	if err != nil {
+		return response, ErrTransactionOpen
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(tx.Rebind("DELETE FROM documents WHERE deleted_at <= ?"), before.UTC())
+	if err != nil {
+		return response, err
+	}
+	if response.Documents, err = result.RowsAffected(); err != nil {
+		return response, err
+	}
+
+	result, err = tx.Exec(tx.Rebind("DELETE FROM tags WHERE deleted_at <= ?"), before.UTC())
+	if err != nil {
+		return response, err
+	}
+	if response.Tags, err = result.RowsAffected(); err != nil {
+		return response, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
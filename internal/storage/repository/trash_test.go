@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func Test_Document_Trash(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+	tagRepository := repository.tagRepository.(*TagRepository)
+	trashRepository := NewTrashRepository(repository.db)
+
+	tag, _ := tagRepository.Create(models.CreateTagRequest{Name: "tag"})
+	document, _ := repository.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{tag}})
+	kept, _ := repository.Create(models.CreateDocumentRequest{Name: "kept", Body: "body"})
+
+	require.NoError(t, repository.Delete(document.ID))
+
+	documents, err := repository.List()
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	require.Equal(t, kept.ID, documents[0].ID)
+
+	documents, err = repository.ReadMany([]models.ID{document.ID})
+	require.NoError(t, err)
+	require.Empty(t, documents)
+
+	unassigned, _ := tagRepository.Read(tag.ID)
+	require.False(t, unassigned.Assigned, "tag of document in trash must not be assigned")
+
+	trash, err := trashRepository.List()
+	require.NoError(t, err)
+	require.Len(t, trash.Documents, 1)
+	require.Equal(t, document.ID, trash.Documents[0].ID)
+	require.False(t, trash.Documents[0].DeletedAt.IsZero())
+	require.Empty(t, trash.Tags)
+
+	require.NoError(t, trashRepository.RestoreDocument(document.ID))
+	require.ErrorIs(t, trashRepository.RestoreDocument(document.ID), ErrNotInTrash)
+
+	restored, err := repository.Read(document.ID)
+	require.NoError(t, err)
+	require.Len(t, restored.Tags, 1, "tags must be assigned again after restore")
+	require.True(t, restored.Tags[0].Assigned)
+}
+
+func Test_Tag_Trash(t *testing.T) {
+	repository, cleanupFunc := newTestTagRepository()
+	defer cleanupFunc()
+	documentRepository := NewDocumentRepository(repository.db, repository)
+	trashRepository := NewTrashRepository(repository.db)
+
+	sport, _ := repository.Create(models.CreateTagRequest{Name: "sport"})
+	football, _ := repository.Create(models.CreateTagRequest{Name: "football", ParentID: null.IntFrom(sport.ID)})
+	repository.CreateAlias(sport.ID, models.CreateTagAliasRequest{Name: "sports"})
+	document, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "name", Body: "body", Tags: []models.TagResponse{sport}})
+
+	require.NoError(t, repository.Delete(sport.ID))
+
+	tags, err := repository.List()
+	require.NoError(t, err)
+	require.Equal(t, []models.TagResponse{{ID: football.ID, Name: "football"}}, tags, "child of tag in trash must be shown as root")
+
+	read, err := documentRepository.Read(document.ID)
+	require.NoError(t, err)
+	require.Empty(t, read.Tags)
+
+	_, err = repository.Resolve(nil, []models.TagResponse{{Name: "sports"}})
+	require.ErrorIs(t, err, ErrTagNotFound, "aliases of tag in trash must not be resolved")
+
+	_, err = repository.Create(models.CreateTagRequest{Name: "sport"})
+	require.ErrorIs(t, err, ErrTagNameTaken)
+
+	trash, err := trashRepository.List()
+	require.NoError(t, err)
+	require.Len(t, trash.Tags, 1)
+	require.Equal(t, sport.ID, trash.Tags[0].ID)
+
+	require.NoError(t, trashRepository.RestoreTag(sport.ID))
+
+	restoredChild, err := repository.Read(football.ID)
+	require.NoError(t, err)
+	require.Equal(t, null.IntFrom(sport.ID), restoredChild.ParentID, "hierarchy must be restored")
+
+	read, err = documentRepository.Read(document.ID)
+	require.NoError(t, err)
+	require.Len(t, read.Tags, 1)
+	require.Equal(t, sport.ID, read.Tags[0].ID)
+}
+
+func Test_Trash_Purge(t *testing.T) {
+	repository, cleanupFunc := testDocumentRepository()
+	defer cleanupFunc()
+	tagRepository := repository.tagRepository.(*TagRepository)
+	trashRepository := NewTrashRepository(repository.db)
+
+	parent, _ := tagRepository.Create(models.CreateTagRequest{Name: "parent"})
+	child, _ := tagRepository.Create(models.CreateTagRequest{Name: "child", ParentID: null.IntFrom(parent.ID)})
+	document, _ := repository.Create(models.CreateDocumentRequest{Name: "name", Body: "body"})
+	require.NoError(t, tagRepository.Delete(parent.ID))
+	require.NoError(t, repository.Delete(document.ID))
+
+	result, err := trashRepository.Purge(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, models.PurgeResult{}, result, "items deleted after retention bound must be kept")
+
+	result, err = trashRepository.Purge(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, models.PurgeResult{Documents: 1, Tags: 1}, result)
+
+	trash, err := trashRepository.List()
+	require.NoError(t, err)
+	require.Empty(t, trash.Documents)
+	require.Empty(t, trash.Tags)
+
+	require.ErrorIs(t, trashRepository.RestoreTag(parent.ID), ErrNotInTrash)
+
+	var parentID null.Int
+	require.NoError(t, repository.db.Get(&parentID, "SELECT parent_id FROM tags WHERE id = ?", child.ID))
+	require.False(t, parentID.Valid, "children of purged tag must become roots")
+}
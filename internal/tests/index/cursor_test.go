@@ -0,0 +1,128 @@
+package utilities
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+func cursorTestDocuments(count int) (documents []models.DocumentResponse) {
+	for i := 1; i <= count; i++ {
+		documents = append(documents, models.DocumentResponse{
+			ID:   int64(i),
+			Name: fmt.Sprintf("документ %d", i%3),
+			Body: "текст",
+		})
+	}
+	return documents
+}
+
+// Walks all pages with cursors and returns found document ids in order
+func findAllWithCursor(t *testing.T, service *indexService.IndexService, request indexService.SearchDocumentRequest) (IDs []models.ID) {
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 100, "cursor does not advance")
+
+		searchResponse, err := service.Find(&request)
+		require.NoError(t, err)
+		for _, document := range searchResponse.Documents {
+			IDs = append(IDs, document.ID)
+		}
+		if searchResponse.NextCursor == "" {
+			return IDs
+		}
+		request.Cursor = searchResponse.NextCursor
+	}
+}
+
+func Test_Find_Cursor(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(cursorTestDocuments(23))
+	defer cleanupFunc()
+
+	testCases := []indexService.SearchDocumentRequest{
+		{PageSize: 5},
+		{PageSize: 5, Query: "текст"},
+		{PageSize: 4, Sort: []string{"name"}},
+		{PageSize: 7, Sort: []string{"-name", "-id"}},
+		{PageSize: 23},
+	}
+
+	for _, testCase := range testCases {
+		pageRequest := testCase
+		pageRequest.PageSize = 100
+		expected, err := service.Find(&pageRequest)
+		require.NoError(t, err)
+		require.Empty(t, expected.NextCursor, "last page must not have cursor")
+
+		expectedIDs := make([]models.ID, 0, len(expected.Documents))
+		for _, document := range expected.Documents {
+			expectedIDs = append(expectedIDs, document.ID)
+		}
+		require.Len(t, expectedIDs, 23)
+		require.Equal(t, expectedIDs, findAllWithCursor(t, service, testCase), "request %+v", testCase)
+	}
+}
+
+func Test_Find_Cursor_Stable_On_Insert(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(cursorTestDocuments(10))
+	defer cleanupFunc()
+
+	firstPage, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 4, Sort: []string{"id"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, firstPage.NextCursor)
+
+	// Document which sorts before current page must not shift next pages
+	require.NoError(t, service.Index([]models.DocumentResponse{{ID: 0, Name: "новый", Body: "текст"}}))
+
+	secondPage, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 4, Sort: []string{"id"}, Cursor: firstPage.NextCursor})
+	require.NoError(t, err)
+	actual := make([]models.ID, 0, len(secondPage.Documents))
+	for _, document := range secondPage.Documents {
+		actual = append(actual, document.ID)
+	}
+	require.Equal(t, []models.ID{5, 6, 7, 8}, actual)
+}
+
+func Test_Find_Cursor_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(cursorTestDocuments(10))
+	defer cleanupFunc()
+
+	firstPage, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 4, Sort: []string{"name"}})
+	require.NoError(t, err)
+
+	for _, request := range []indexService.SearchDocumentRequest{
+		{PageSize: 4, Cursor: "not a cursor"},
+		{PageSize: 4, Cursor: "bm90IGpzb24"},
+		{PageSize: 4, Sort: []string{"-name"}, Cursor: firstPage.NextCursor},
+	} {
+		_, err := service.Find(&request)
+		require.ErrorIs(t, err, indexService.ErrInvalidCursor, "cursor %s", request.Cursor)
+	}
+}
+
+func Test_Find_Cursor_Recency_Profile(t *testing.T) {
+	now := time.Now()
+	documents := cursorTestDocuments(12)
+	for i := range documents {
+		documents[i].CreatedAt = now.Add(-time.Duration(i) * time.Hour)
+	}
+	service, cleanupFunc := NewTestMemIndexService(documents)
+	defer cleanupFunc()
+	service.RelevanceProfiles = map[string]indexService.RelevanceProfile{
+		"recency": {Recency: &indexService.RecencyDecay{HalfLife: time.Hour, Weight: 1}},
+	}
+
+	expected, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 12, Query: "текст", Profile: "recency"})
+	require.NoError(t, err)
+	expectedIDs := make([]models.ID, 0, len(expected.Documents))
+	for _, document := range expected.Documents {
+		expectedIDs = append(expectedIDs, document.ID)
+	}
+
+	// Scores decay between pages, cursor must keep time of first page to continue the same ranking
+	request := indexService.SearchDocumentRequest{PageSize: 3, Query: "текст", Profile: "recency"}
+	require.Equal(t, expectedIDs, findAllWithCursor(t, service, request))
+}
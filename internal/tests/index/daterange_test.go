@@ -0,0 +1,125 @@
+package utilities
+
+import (
+	"testing"
+	"time"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+var dateRangeTestDocuments = []models.DocumentResponse{
+	{ID: 1, Name: "январский документ", Body: "тело", CreatedAt: date(2024, time.January, 10), UpdatedAt: date(2024, time.March, 1)},
+	{ID: 2, Name: "второй январский документ", Body: "тело", CreatedAt: date(2024, time.January, 10), UpdatedAt: date(2024, time.January, 10)},
+	{ID: 3, Name: "февральский документ", Body: "тело", CreatedAt: date(2024, time.February, 5), UpdatedAt: date(2024, time.February, 5)},
+	{ID: 4, Name: "мартовский документ", Body: "тело", CreatedAt: date(2024, time.March, 20), UpdatedAt: date(2024, time.March, 20)},
+}
+
+func Test_Find_Date_Ranges(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(dateRangeTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		name     string
+		request  indexService.SearchDocumentRequest
+		expected []models.ID
+	}{
+		{
+			name:     "created from",
+			request:  indexService.SearchDocumentRequest{CreatedFrom: date(2024, time.February, 5)},
+			expected: []models.ID{3, 4},
+		},
+		{
+			name:     "created to",
+			request:  indexService.SearchDocumentRequest{CreatedTo: date(2024, time.February, 5)},
+			expected: []models.ID{1, 2, 3},
+		},
+		{
+			name:     "created within",
+			request:  indexService.SearchDocumentRequest{CreatedFrom: date(2024, time.January, 11), CreatedTo: date(2024, time.March, 1)},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "updated and created",
+			request:  indexService.SearchDocumentRequest{CreatedTo: date(2024, time.January, 31), UpdatedFrom: date(2024, time.February, 1)},
+			expected: []models.ID{1},
+		},
+		{
+			name:     "with query",
+			request:  indexService.SearchDocumentRequest{Query: "январский", UpdatedTo: date(2024, time.January, 31)},
+			expected: []models.ID{2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.request.PageSize = 10
+		testCase.request.Sort = []string{"id"}
+		searchResponse, err := service.Find(&testCase.request)
+		require.NoError(t, err, testCase.name)
+
+		actual := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, testCase.name)
+	}
+}
+
+func Test_Find_Date_Range_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(dateRangeTestDocuments)
+	defer cleanupFunc()
+
+	_, err := service.Find(&indexService.SearchDocumentRequest{
+		PageSize:    10,
+		CreatedFrom: date(2024, time.March, 1),
+		CreatedTo:   date(2024, time.January, 1),
+	})
+	require.ErrorIs(t, err, indexService.ErrInvalidDateRange)
+}
+
+func Test_Find_Date_Histogram(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(dateRangeTestDocuments)
+	defer cleanupFunc()
+
+	midnight := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		PageSize:      10,
+		DateHistogram: &indexService.DateHistogramRequest{Interval: indexService.DateHistogramIntervalMonth},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []indexService.DateBucket{
+		{Start: midnight(2024, time.January, 1), End: midnight(2024, time.February, 1), DocumentCount: 2},
+		{Start: midnight(2024, time.February, 1), End: midnight(2024, time.March, 1), DocumentCount: 1},
+		{Start: midnight(2024, time.March, 1), End: midnight(2024, time.April, 1), DocumentCount: 1},
+	}, searchResponse.DateHistogram)
+
+	searchResponse, err = service.Find(&indexService.SearchDocumentRequest{
+		PageSize:      10,
+		UpdatedFrom:   date(2024, time.February, 1),
+		DateHistogram: &indexService.DateHistogramRequest{Field: indexService.DateHistogramFieldUpdated, Interval: indexService.DateHistogramIntervalDay},
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResponse.DateHistogram, 45, "buckets must cover every day from first to last updated document")
+	require.Equal(t, indexService.DateBucket{Start: midnight(2024, time.February, 5), End: midnight(2024, time.February, 6), DocumentCount: 1}, searchResponse.DateHistogram[0])
+	require.Equal(t, indexService.DateBucket{Start: midnight(2024, time.March, 20), End: midnight(2024, time.March, 21), DocumentCount: 1}, searchResponse.DateHistogram[44])
+
+	var total indexService.DocumentCount
+	for _, bucket := range searchResponse.DateHistogram {
+		total += bucket.DocumentCount
+	}
+	require.Equal(t, 3, total)
+
+	_, err = service.Find(&indexService.SearchDocumentRequest{
+		PageSize:      10,
+		DateHistogram: &indexService.DateHistogramRequest{Interval: "week"},
+	})
+	require.ErrorIs(t, err, indexService.ErrInvalidDateHistogram)
+}
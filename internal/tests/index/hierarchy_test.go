@@ -0,0 +1,84 @@
+package utilities
+
+import (
+	"slices"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+var (
+	sportTag         = models.TagResponse{ID: 1, Name: "спорт", Assigned: true}
+	footballTag      = models.TagResponse{ID: 2, Name: "футбол", Assigned: true, ParentID: null.IntFrom(1)}
+	premierLeagueTag = models.TagResponse{ID: 3, Name: "премьер-лига", Assigned: true, ParentID: null.IntFrom(2)}
+)
+
+var hierarchyTestDocuments = []models.DocumentResponse{
+	{ID: 1, Name: "Олимпиада", Body: "спорт", Tags: []models.TagResponse{sportTag}},
+	{ID: 2, Name: "Чемпионат мира", Body: "футбол", Tags: []models.TagResponse{footballTag}},
+	{ID: 3, Name: "Манчестер Юнайтед", Body: "премьер-лига", Tags: []models.TagResponse{premierLeagueTag}},
+	{ID: 4, Name: "Дерби", Body: "футбол и премьер-лига", Tags: []models.TagResponse{footballTag, premierLeagueTag}},
+}
+
+func Test_Find_IncludeDescendants(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(hierarchyTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		request  indexService.SearchDocumentRequest
+		expected []models.ID
+	}{
+		{
+			request:  indexService.SearchDocumentRequest{Tags: []string{"спорт"}},
+			expected: []models.ID{1},
+		},
+		{
+			request:  indexService.SearchDocumentRequest{Tags: []string{"спорт"}, IncludeDescendants: true},
+			expected: []models.ID{1, 2, 3, 4},
+		},
+		{
+			request:  indexService.SearchDocumentRequest{Tags: []string{"футбол"}, IncludeDescendants: true},
+			expected: []models.ID{2, 3, 4},
+		},
+		{
+			request:  indexService.SearchDocumentRequest{TagFilter: "спорт & !премьер-лига", IncludeDescendants: true},
+			expected: []models.ID{1, 2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.request.PageSize = 10
+		testCase.request.Sort = []string{"id"}
+		searchResponse, err := service.Find(&testCase.request)
+		require.NoError(t, err)
+
+		var actual []models.ID
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, "request %+v", testCase.request)
+	}
+}
+
+func Test_Find_RollupDocumentCount(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(hierarchyTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 10})
+	require.NoError(t, err)
+
+	expected := map[string][2]indexService.DocumentCount{
+		"спорт":        {1, 4},
+		"футбол":       {2, 3},
+		"премьер-лига": {2, 2},
+	}
+	for name, counts := range expected {
+		bucketIndex := slices.IndexFunc(searchResponse.Tags, func(bucket indexService.TagBucket) bool { return bucket.Name == name })
+		require.NotEqual(t, -1, bucketIndex, name)
+		require.Equal(t, counts[0], searchResponse.Tags[bucketIndex].DocumentCount, name)
+		require.Equal(t, counts[1], searchResponse.Tags[bucketIndex].RollupDocumentCount, name)
+	}
+}
@@ -0,0 +1,82 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var highlightTestDocuments = []models.DocumentResponse{
+	{
+		ID:   1,
+		Name: "Футбольный клуб выиграл кубок",
+		Body: "Вчера вечером футбольный клуб из Москвы выиграл кубок страны. Болельщики праздновали победу до утра.",
+		Tags: []models.TagResponse{{ID: 1, Name: "спорт"}},
+	},
+	{
+		ID:   2,
+		Name: "Курс рубля",
+		Body: "Центральный банк опубликовал новый курс рубля.",
+		Tags: []models.TagResponse{{ID: 2, Name: "экономика"}},
+	},
+}
+
+func Test_Find_Highlight(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(highlightTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Query:     "кубок",
+		PageSize:  10,
+		Highlight: &indexService.HighlightRequest{},
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResponse.Documents, 1)
+
+	highlight := searchResponse.Highlights[1]
+	require.Equal(t, []string{"Футбольный клуб выиграл <mark>кубок</mark>"}, highlight["name"])
+	require.Len(t, highlight["body"], 1)
+	require.Contains(t, highlight["body"][0], "<mark>кубок</mark>")
+}
+
+func Test_Find_Highlight_Options(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(highlightTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Query:    "футбольный",
+		PageSize: 10,
+		Highlight: &indexService.HighlightRequest{
+			Style:        indexService.HighlightStylePlain,
+			FragmentSize: 30,
+			Fragments:    2,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, fragment := range searchResponse.Highlights[1]["body"] {
+		require.Contains(t, fragment, "<start>")
+	}
+}
+
+func Test_Find_Highlight_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(highlightTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []indexService.HighlightRequest{
+		{Style: "unknown"},
+		{FragmentSize: indexService.MaxHighlightFragmentSize + 1},
+		{Fragments: -1},
+	}
+
+	for _, highlightRequest := range testCases {
+		_, err := service.Find(&indexService.SearchDocumentRequest{
+			Query:     "кубок",
+			PageSize:  10,
+			Highlight: &highlightRequest,
+		})
+		require.ErrorIs(t, err, indexService.ErrInvalidHighlightRequest)
+	}
+}
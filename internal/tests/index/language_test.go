@@ -0,0 +1,88 @@
+package utilities
+
+import (
+	"slices"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var languageTestDocuments = []models.DocumentResponse{
+	{
+		ID:       1,
+		Name:     "Футбольные клубы",
+		Body:     "Московские клубы выиграли матчи чемпионата.",
+		Language: models.LanguageRussian,
+		Tags:     []models.TagResponse{{ID: 1, Name: "спорт"}},
+	},
+	{
+		ID:       2,
+		Name:     "Football clubs",
+		Body:     "London clubs were winning their matches in the championship.",
+		Language: models.LanguageEnglish,
+		Tags:     []models.TagResponse{{ID: 1, Name: "спорт"}},
+	},
+	{
+		// Language of document without language is detected from body
+		ID:   3,
+		Name: "Running shoes",
+		Body: "Shoes for runners who are running every morning.",
+	},
+}
+
+func Test_Find_Language(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(languageTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		query    string
+		expected []models.ID
+	}{
+		{query: "клуб", expected: []models.ID{1}},
+		{query: "матч", expected: []models.ID{1}},
+		{query: "club", expected: []models.ID{2}},
+		{query: "match", expected: []models.ID{2}},
+		{query: "runs", expected: []models.ID{3}},
+		{query: "name:клуб", expected: []models.ID{1}},
+		{query: "body:football", expected: []models.ID{}},
+		{query: "+club +london", expected: []models.ID{2}},
+		{query: "спорт", expected: []models.ID{1, 2}},
+		{query: "language:en", expected: []models.ID{2, 3}},
+	}
+
+	for _, testCase := range testCases {
+		searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+			Query:    testCase.query,
+			PageSize: 10,
+		})
+		require.NoError(t, err, testCase.query)
+
+		actual := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		slices.Sort(actual)
+		require.Equal(t, testCase.expected, actual, testCase.query)
+	}
+}
+
+func Test_Find_Language_Highlight(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(languageTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Query:     "run",
+		PageSize:  10,
+		Highlight: &indexService.HighlightRequest{},
+	})
+	require.NoError(t, err)
+	require.Len(t, searchResponse.Documents, 1)
+
+	// Stemmed terms are highlighted in stored text of name and body
+	highlight := searchResponse.Highlights[3]
+	require.Equal(t, []string{"<mark>Running</mark> shoes"}, highlight["name"])
+	require.Len(t, highlight["body"], 1)
+	require.Contains(t, highlight["body"][0], "<mark>running</mark>")
+}
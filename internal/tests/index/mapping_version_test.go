@@ -0,0 +1,30 @@
+package utilities
+
+import (
+	"path/filepath"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CheckMappingHash(t *testing.T) {
+	index, err := indexService.NewIndex(filepath.Join(t.TempDir(), "index.bleve"))
+	require.NoError(t, err)
+	defer index.Close()
+
+	require.NoError(t, indexService.CheckMappingHash(index), "new index must have current mapping hash")
+
+	require.NoError(t, index.SetInternal([]byte("mappingHash"), []byte("outdated")))
+	require.ErrorIs(t, indexService.CheckMappingHash(index), indexService.ErrMappingChanged)
+
+	require.NoError(t, indexService.StoreMappingHash(index))
+	require.NoError(t, indexService.CheckMappingHash(index))
+
+	unversionedIndex, err := bleve.NewMemOnly(indexService.GetIndexMapping())
+	require.NoError(t, err)
+	defer unversionedIndex.Close()
+
+	require.ErrorIs(t, indexService.CheckMappingHash(unversionedIndex), indexService.ErrMappingChanged, "index without hash must be treated as changed")
+}
@@ -0,0 +1,155 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Find_Pagination(t *testing.T) {
+	testCases := []struct {
+		name        string
+		documents   int
+		pageSize    int
+		pageNumber  int // zero based as in request
+		expectedIDs []models.ID
+		expected    indexService.SearchResponse
+	}{
+		{
+			name:     "empty",
+			pageSize: 10,
+			expected: indexService.SearchResponse{Page: 1, PageSize: 10},
+		},
+		{
+			name:       "empty out of bounds",
+			pageSize:   10,
+			pageNumber: 3,
+			expected:   indexService.SearchResponse{Page: 1, PageSize: 10, RequestPageIsOutOfBounds: true},
+		},
+		{
+			name:        "single page",
+			documents:   3,
+			pageSize:    10,
+			expectedIDs: []models.ID{1, 2, 3},
+			expected:    indexService.SearchResponse{Page: 1, PageSize: 10, TotalPages: 1, Pages: 1, DocumentsFound: 3},
+		},
+		{
+			name:        "first of many",
+			documents:   7,
+			pageSize:    3,
+			expectedIDs: []models.ID{1, 2, 3},
+			expected:    indexService.SearchResponse{Page: 1, PageSize: 3, TotalPages: 3, Pages: 3, DocumentsFound: 7, HasNext: true},
+		},
+		{
+			name:        "middle",
+			documents:   7,
+			pageSize:    3,
+			pageNumber:  1,
+			expectedIDs: []models.ID{4, 5, 6},
+			expected:    indexService.SearchResponse{Page: 2, PageSize: 3, TotalPages: 3, Pages: 3, DocumentsFound: 7, HasNext: true, HasPrev: true},
+		},
+		{
+			name:        "last partial",
+			documents:   7,
+			pageSize:    3,
+			pageNumber:  2,
+			expectedIDs: []models.ID{7},
+			expected:    indexService.SearchResponse{Page: 3, PageSize: 3, TotalPages: 3, Pages: 3, DocumentsFound: 7, HasPrev: true},
+		},
+		{
+			name:        "exact multiple last",
+			documents:   6,
+			pageSize:    3,
+			pageNumber:  1,
+			expectedIDs: []models.ID{4, 5, 6},
+			expected:    indexService.SearchResponse{Page: 2, PageSize: 3, TotalPages: 2, Pages: 2, DocumentsFound: 6, HasPrev: true},
+		},
+		{
+			name:        "exact multiple overflow by one",
+			documents:   6,
+			pageSize:    3,
+			pageNumber:  2,
+			expectedIDs: []models.ID{4, 5, 6},
+			expected:    indexService.SearchResponse{Page: 2, PageSize: 3, TotalPages: 2, Pages: 2, DocumentsFound: 6, HasPrev: true, RequestPageIsOutOfBounds: true},
+		},
+		{
+			name:        "overflow",
+			documents:   7,
+			pageSize:    3,
+			pageNumber:  50,
+			expectedIDs: []models.ID{7},
+			expected:    indexService.SearchResponse{Page: 3, PageSize: 3, TotalPages: 3, Pages: 3, DocumentsFound: 7, HasPrev: true, RequestPageIsOutOfBounds: true},
+		},
+		{
+			name:        "overflow single page",
+			documents:   2,
+			pageSize:    5,
+			pageNumber:  1,
+			expectedIDs: []models.ID{1, 2},
+			expected:    indexService.SearchResponse{Page: 1, PageSize: 5, TotalPages: 1, Pages: 1, DocumentsFound: 2, RequestPageIsOutOfBounds: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			service, cleanupFunc := NewTestMemIndexService(cursorTestDocuments(testCase.documents))
+			defer cleanupFunc()
+
+			searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+				PageSize:   testCase.pageSize,
+				PageNumber: testCase.pageNumber,
+				Sort:       []string{"id"},
+			})
+			require.NoError(t, err)
+
+			var actualIDs []models.ID
+			for _, document := range searchResponse.Documents {
+				actualIDs = append(actualIDs, document.ID)
+			}
+			require.Equal(t, testCase.expectedIDs, actualIDs)
+
+			require.Equal(t, testCase.expected, indexService.SearchResponse{
+				DocumentsFound:           searchResponse.DocumentsFound,
+				Pages:                    searchResponse.Pages,
+				RequestPageIsOutOfBounds: searchResponse.RequestPageIsOutOfBounds,
+				Page:                     searchResponse.Page,
+				PageSize:                 searchResponse.PageSize,
+				TotalPages:               searchResponse.TotalPages,
+				HasNext:                  searchResponse.HasNext,
+				HasPrev:                  searchResponse.HasPrev,
+			})
+		})
+	}
+}
+
+func Test_Find_Pagination_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(cursorTestDocuments(3))
+	defer cleanupFunc()
+	service.MaxPageSize = 20
+
+	testCases := []struct {
+		pageSize   int
+		pageNumber int
+	}{
+		{pageSize: -1},
+		{pageSize: 21},
+		{pageSize: 10, pageNumber: -1},
+	}
+
+	for _, testCase := range testCases {
+		_, err := service.Find(&indexService.SearchDocumentRequest{
+			PageSize:   testCase.pageSize,
+			PageNumber: testCase.pageNumber,
+		})
+		require.ErrorIs(t, err, indexService.ErrInvalidPagination, "%+v", testCase)
+	}
+
+	_, err := service.Find(&indexService.SearchDocumentRequest{PageSize: 20})
+	require.NoError(t, err)
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{})
+	require.NoError(t, err)
+	require.Equal(t, indexService.DefaultPageSize, searchResponse.PageSize, "zero page size means default")
+}
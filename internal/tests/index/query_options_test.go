@@ -0,0 +1,165 @@
+package utilities
+
+import (
+	"slices"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var queryOptionsTestDocuments = []models.DocumentResponse{
+	{
+		ID:       1,
+		Name:     "Футбольный клуб выиграл кубок",
+		Body:     "Московский клуб выиграл кубок страны в финальном матче.",
+		Language: models.LanguageRussian,
+	},
+	{
+		ID:       2,
+		Name:     "Кубок по хоккею",
+		Body:     "Хоккейный клуб проиграл финал.",
+		Language: models.LanguageRussian,
+	},
+	{
+		ID:       3,
+		Name:     "Football club wins the cup",
+		Body:     "The London club won the national cup in the final match.",
+		Language: models.LanguageEnglish,
+	},
+	{
+		ID:       4,
+		Name:     "Cup of tea",
+		Body:     "A cup of green tea every morning.",
+		Language: models.LanguageEnglish,
+	},
+}
+
+func Test_Find_Query_Options(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(queryOptionsTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		name     string
+		request  indexService.SearchDocumentRequest
+		expected []models.ID
+	}{
+		{
+			name:     "phrase",
+			request:  indexService.SearchDocumentRequest{Query: "выиграл кубок", Phrase: true},
+			expected: []models.ID{1},
+		},
+		{
+			name:     "phrase with stop word",
+			request:  indexService.SearchDocumentRequest{Query: "cup of tea", Phrase: true},
+			expected: []models.ID{4},
+		},
+		{
+			name:     "phrase without slop",
+			request:  indexService.SearchDocumentRequest{Query: "клуб кубок", Phrase: true},
+			expected: []models.ID{},
+		},
+		{
+			name:     "phrase with slop",
+			request:  indexService.SearchDocumentRequest{Query: "клуб кубок", Phrase: true, Slop: 1},
+			expected: []models.ID{1},
+		},
+		{
+			name:     "phrase with reversed terms",
+			request:  indexService.SearchDocumentRequest{Query: "cup club", Phrase: true, Slop: 2},
+			expected: []models.ID{},
+		},
+		{
+			name:     "phrase with reversed terms and enough slop",
+			request:  indexService.SearchDocumentRequest{Query: "cup club", Phrase: true, Slop: 4},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "fuzzy phrase with slop",
+			request:  indexService.SearchDocumentRequest{Query: "club cap", Phrase: true, Slop: 3, Fuzziness: 1},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "without fuzziness",
+			request:  indexService.SearchDocumentRequest{Query: "tee", Operator: indexService.QueryOperatorOr},
+			expected: []models.ID{},
+		},
+		{
+			name:     "fuzziness",
+			request:  indexService.SearchDocumentRequest{Query: "tee", Fuzziness: 1},
+			expected: []models.ID{4},
+		},
+		{
+			name:     "or operator",
+			request:  indexService.SearchDocumentRequest{Query: "club cup", Operator: indexService.QueryOperatorOr},
+			expected: []models.ID{3, 4},
+		},
+		{
+			name:     "and operator",
+			request:  indexService.SearchDocumentRequest{Query: "club cup", Operator: indexService.QueryOperatorAnd},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "and operator across fields",
+			request:  indexService.SearchDocumentRequest{Query: "football london", Operator: indexService.QueryOperatorAnd},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "and operator with stop word",
+			request:  indexService.SearchDocumentRequest{Query: "the football", Operator: indexService.QueryOperatorAnd},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "name field",
+			request:  indexService.SearchDocumentRequest{Query: "london", Fields: []string{"name"}},
+			expected: []models.ID{},
+		},
+		{
+			name:     "body field",
+			request:  indexService.SearchDocumentRequest{Query: "london", Fields: []string{"body"}},
+			expected: []models.ID{3},
+		},
+		{
+			name:     "query string",
+			request:  indexService.SearchDocumentRequest{Query: "+football +london"},
+			expected: []models.ID{3},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.request.PageSize = 10
+		searchResponse, err := service.Find(&testCase.request)
+		require.NoError(t, err, testCase.name)
+
+		actual := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		slices.Sort(actual)
+		require.Equal(t, testCase.expected, actual, testCase.name)
+	}
+}
+
+func Test_Find_Query_Options_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(queryOptionsTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []indexService.SearchDocumentRequest{
+		{Fuzziness: indexService.MaxFuzziness + 1},
+		{Fuzziness: -1},
+		{Slop: 1},
+		{Phrase: true, Slop: indexService.MaxSlop + 1},
+		{Operator: "xor"},
+		{Phrase: true, Operator: indexService.QueryOperatorAnd},
+		{Fields: []string{"tags"}},
+		{Fields: []string{"name", "name"}},
+	}
+
+	for _, searchRequest := range testCases {
+		searchRequest.Query = "club"
+		searchRequest.PageSize = 10
+		_, err := service.Find(&searchRequest)
+		require.ErrorIs(t, err, indexService.ErrInvalidQueryOptions)
+	}
+}
@@ -0,0 +1,49 @@
+package utilities
+
+import (
+	"path/filepath"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Rebuild(t *testing.T) {
+	documents := []models.DocumentResponse{
+		{ID: 1, Name: "Первый", Body: "первый документ"},
+		{ID: 2, Name: "Второй", Body: "второй документ"},
+	}
+
+	path := filepath.Join(t.TempDir(), "index.bleve")
+	index, err := bleve.New(path, indexService.GetIndexMapping())
+	require.NoError(t, err)
+
+	documentRepository := NewMockDocumentRepository(documents)
+	service := indexService.NewIndexService(index, documentRepository, NewMockTagRepository(documents))
+	require.NoError(t, service.Index(documents))
+
+	delete(documentRepository.store, 2)
+	documentRepository.store[3] = models.DocumentResponse{ID: 3, Name: "Третий", Body: "третий документ"}
+
+	require.NoError(t, service.Rebuild(documentRepository, path))
+
+	status := service.RebuildStatus()
+	require.False(t, status.Running)
+	require.Empty(t, status.Error)
+	require.Equal(t, 2, status.IndexedDocuments)
+
+	report, err := service.Verify(documentRepository, false)
+	require.NoError(t, err)
+	require.True(t, report.Consistent(), "rebuilt index must contain database documents only")
+
+	require.NoDirExists(t, path+".new")
+	require.NoDirExists(t, path+".old")
+
+	response, err := service.Find(&indexService.SearchDocumentRequest{Query: "третий", PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Documents, 1, "swapped index must serve requests")
+
+	require.ErrorIs(t, service.Rebuild(documentRepository, ""), indexService.ErrIndexPathRequired)
+}
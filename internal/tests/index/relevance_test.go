@@ -0,0 +1,190 @@
+package utilities
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+func relevanceTestDocuments(now time.Time) []models.DocumentResponse {
+	yearAgo := now.AddDate(-1, 0, 0)
+	return []models.DocumentResponse{
+		{ID: 1, Name: "Football", Body: "Weekly sports review.", CreatedAt: now},
+		{ID: 2, Name: "Weekly sports review", Body: "Football", CreatedAt: now},
+		{ID: 3, Name: "Tennis news", Body: "Tennis", CreatedAt: now, Tags: []models.TagResponse{{ID: 1, Name: "Sport"}}},
+		{ID: 4, Name: "Tennis news", Body: "Tennis", CreatedAt: now, Tags: []models.TagResponse{{ID: 2, Name: "Other"}}},
+		{ID: 5, Name: "Chess news", Body: "Chess", CreatedAt: yearAgo},
+		{ID: 6, Name: "Chess news", Body: "Chess", CreatedAt: now},
+	}
+}
+
+var relevanceTestProfiles = map[string]indexService.RelevanceProfile{
+	"name":           {FieldBoosts: map[string]float64{"name": 5}},
+	"body":           {FieldBoosts: map[string]float64{"body": 5}},
+	"tags":           {TagBoosts: map[string]float64{"Other": 5}},
+	"recency":        {Recency: &indexService.RecencyDecay{HalfLife: 30 * 24 * time.Hour, Weight: 1}},
+	"recencyIgnored": {Recency: &indexService.RecencyDecay{HalfLife: 30 * 24 * time.Hour, Weight: 0}},
+}
+
+func Test_Find_Relevance_Profile(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(relevanceTestDocuments(time.Now()))
+	defer cleanupFunc()
+	service.RelevanceProfiles = relevanceTestProfiles
+
+	testCases := []struct {
+		query    string
+		profile  string
+		expected []models.ID
+	}{
+		{query: "football", profile: "name", expected: []models.ID{1, 2}},
+		{query: "football", profile: "body", expected: []models.ID{2, 1}},
+		{query: "name:football body:football", profile: "body", expected: []models.ID{2, 1}},
+		{query: "tennis", profile: "", expected: []models.ID{3, 4}},
+		{query: "tennis", profile: "tags", expected: []models.ID{4, 3}},
+		{query: "chess", profile: "", expected: []models.ID{5, 6}},
+		{query: "chess", profile: "recency", expected: []models.ID{6, 5}},
+		{query: "chess", profile: "recencyIgnored", expected: []models.ID{5, 6}},
+	}
+
+	for _, testCase := range testCases {
+		searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+			Query:    testCase.query,
+			Profile:  testCase.profile,
+			PageSize: 10,
+		})
+		require.NoError(t, err, testCase.profile)
+
+		actual := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, "query '%s' with profile '%s'", testCase.query, testCase.profile)
+	}
+
+	// Query options build their own queries of language fields which are boosted too
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Query:    "football",
+		Operator: indexService.QueryOperatorOr,
+		Profile:  "body",
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.ID(2), searchResponse.Documents[0].ID)
+
+	_, err = service.Find(&indexService.SearchDocumentRequest{Query: "football", Profile: "unknown", PageSize: 10})
+	require.ErrorIs(t, err, indexService.ErrUnknownRelevanceProfile)
+}
+
+func Test_Find_Default_Relevance_Profile(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(relevanceTestDocuments(time.Now()))
+	defer cleanupFunc()
+	service.RelevanceProfiles = map[string]indexService.RelevanceProfile{
+		indexService.DefaultRelevanceProfileName: relevanceTestProfiles["recency"],
+	}
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{Query: "chess", PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, models.ID(6), searchResponse.Documents[0].ID)
+}
+
+func Test_Evaluate_Relevance(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(relevanceTestDocuments(time.Now()))
+	defer cleanupFunc()
+	service.RelevanceProfiles = relevanceTestProfiles
+
+	_, err := service.EvaluateRelevance(&indexService.EvaluationRequest{Profile: "body"})
+	require.ErrorIs(t, err, indexService.ErrInvalidEvaluationRequest)
+
+	service.JudgedQueries = []indexService.JudgedQuery{
+		{Query: "football", Judgments: map[models.ID]int{2: 3, 1: 1}},
+		{Query: "chess", Judgments: map[models.ID]int{6: 1}},
+	}
+
+	evaluation, err := service.EvaluateRelevance(&indexService.EvaluationRequest{Profile: "body", K: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, evaluation.K)
+	require.Len(t, evaluation.Queries, 2)
+	require.Equal(t, []models.ID{2, 1}, evaluation.Queries[0].DocumentIDs)
+	require.InDelta(t, 1, evaluation.Queries[0].NDCG, 1e-9)
+	require.InDelta(t, 1, evaluation.Queries[0].Precision, 1e-9)
+	// Relevant document is ranked second
+	require.InDelta(t, 1/math.Log2(3), evaluation.Queries[1].NDCG, 1e-9)
+	require.InDelta(t, 0.5, evaluation.Queries[1].Precision, 1e-9)
+	require.InDelta(t, (1+1/math.Log2(3))/2, evaluation.NDCG, 1e-9)
+	require.InDelta(t, 0.75, evaluation.Precision, 1e-9)
+
+	evaluation, err = service.EvaluateRelevance(&indexService.EvaluationRequest{Profile: "name", K: 2})
+	require.NoError(t, err)
+	require.InDelta(t, (1+7/math.Log2(3))/(7+1/math.Log2(3)), evaluation.Queries[0].NDCG, 1e-9)
+
+	_, err = service.EvaluateRelevance(&indexService.EvaluationRequest{Profile: "unknown"})
+	require.ErrorIs(t, err, indexService.ErrUnknownRelevanceProfile)
+
+	_, err = service.EvaluateRelevance(&indexService.EvaluationRequest{K: service.MaxPageSize + 1})
+	require.ErrorIs(t, err, indexService.ErrInvalidEvaluationRequest)
+}
+
+func Test_Load_Relevance_Profiles(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "profiles.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"default": {
+			"fieldBoosts": {"name": 3, "body": 1},
+			"tagBoosts": {"Политика": 2},
+			"recency": {"halfLife": "720h", "weight": 0.5}
+		},
+		"plain": {}
+	}`), 0644))
+	profiles, err := indexService.LoadRelevanceProfiles(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]indexService.RelevanceProfile{
+		"default": {
+			FieldBoosts: map[string]float64{"name": 3, "body": 1},
+			TagBoosts:   map[string]float64{"Политика": 2},
+			Recency:     &indexService.RecencyDecay{HalfLife: 720 * time.Hour, Weight: 0.5},
+		},
+		"plain": {},
+	}, profiles)
+
+	invalidProfiles := []string{
+		`{"default": {"fieldBoosts": {"tags": 2}}}`,
+		`{"default": {"fieldBoosts": {"name": 0}}}`,
+		`{"default": {"tagBoosts": {"Политика": -1}}}`,
+		`{"default": {"recency": {"halfLife": "month", "weight": 0.5}}}`,
+		`{"default": {"recency": {"halfLife": "720h", "weight": 2}}}`,
+	}
+	for _, invalidProfile := range invalidProfiles {
+		require.NoError(t, os.WriteFile(path, []byte(invalidProfile), 0644))
+		_, err := indexService.LoadRelevanceProfiles(path)
+		require.ErrorIs(t, err, indexService.ErrInvalidRelevanceProfile, invalidProfile)
+	}
+}
+
+func Test_Load_Judged_Queries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "judgments.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"query": "политика", "judgments": {"12": 3, "40": 0}}]`), 0644))
+	judgedQueries, err := indexService.LoadJudgedQueries(path)
+	require.NoError(t, err)
+	require.Equal(t, []indexService.JudgedQuery{
+		{Query: "политика", Judgments: map[models.ID]int{12: 3, 40: 0}},
+	}, judgedQueries)
+
+	invalidJudgedQueries := []string{
+		`[{"judgments": {"12": 3}}]`,
+		`[{"query": "политика", "judgments": {"12": -1}}]`,
+		`[{"query": "политика", "judgments": {"12": 0}}]`,
+	}
+	for _, invalidJudgedQuery := range invalidJudgedQueries {
+		require.NoError(t, os.WriteFile(path, []byte(invalidJudgedQuery), 0644))
+		_, err := indexService.LoadJudgedQueries(path)
+		require.ErrorIs(t, err, indexService.ErrInvalidJudgedQueries, invalidJudgedQuery)
+	}
+}
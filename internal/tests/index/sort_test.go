@@ -0,0 +1,79 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var sortTestDocuments = []models.DocumentResponse{
+	{
+		ID:   1,
+		Name: "в мире животных",
+		Body: "документ без тегов",
+	},
+	{
+		ID:   2,
+		Name: "Арбуз",
+		Body: "документ с двумя тегами",
+		Tags: []models.TagResponse{{ID: 1, Name: "еда"}, {ID: 2, Name: "лето"}},
+	},
+	{
+		ID:   3,
+		Name: "Бег по утрам",
+		Body: "документ с одним тегом",
+		Tags: []models.TagResponse{{ID: 3, Name: "спорт"}},
+	},
+	{
+		ID:   10,
+		Name: "Бег по вечерам",
+		Body: "документ с одним тегом",
+		Tags: []models.TagResponse{{ID: 3, Name: "спорт"}},
+	},
+}
+
+func Test_Find_Sort(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(sortTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		sort     []string
+		expected []models.ID
+	}{
+		{sort: []string{"id"}, expected: []models.ID{1, 2, 3, 10}},
+		{sort: []string{"-id"}, expected: []models.ID{10, 3, 2, 1}},
+		{sort: []string{"name"}, expected: []models.ID{2, 10, 3, 1}},
+		{sort: []string{"-name"}, expected: []models.ID{1, 3, 10, 2}},
+		{sort: []string{"-tags", "name"}, expected: []models.ID{2, 10, 3, 1}},
+		{sort: []string{"tags", "-id"}, expected: []models.ID{1, 10, 3, 2}},
+	}
+
+	for _, testCase := range testCases {
+		searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+			PageSize: 10,
+			Sort:     testCase.sort,
+		})
+		require.NoError(t, err)
+
+		actual := make([]models.ID, 0, len(searchResponse.Documents))
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, "sort %v", testCase.sort)
+	}
+}
+
+func Test_Find_Sort_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(sortTestDocuments)
+	defer cleanupFunc()
+
+	for _, sort := range [][]string{{"body"}, {"name", "-name"}} {
+		_, err := service.Find(&indexService.SearchDocumentRequest{
+			PageSize: 10,
+			Sort:     sort,
+		})
+		require.ErrorIs(t, err, indexService.ErrInvalidSort)
+	}
+}
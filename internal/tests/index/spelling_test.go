@@ -0,0 +1,83 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var spellingTestDocuments = []models.DocumentResponse{
+	{
+		ID:   1,
+		Name: "Политика",
+		Body: "Новости внутренней политики.",
+	},
+	{
+		ID:   2,
+		Name: "Мировая политика",
+		Body: "Переговоры президентов.",
+	},
+	{
+		ID:   3,
+		Name: "Football news",
+		Body: "Results of the championship.",
+	},
+}
+
+func Test_Find_Spelling(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(spellingTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		query          string
+		suggestedQuery string
+		documentsFound int64
+	}{
+		{query: "политка", suggestedQuery: "политика", documentsFound: 0},
+		{query: "name:политка", suggestedQuery: "name:политика", documentsFound: 0},
+		{query: "мировая политка", suggestedQuery: "мировая политика", documentsFound: 1},
+		{query: "resuls championshp", suggestedQuery: "results championship", documentsFound: 0},
+		{query: "политика", suggestedQuery: "", documentsFound: 2},
+		{query: "полит*", suggestedQuery: "", documentsFound: 2},
+		{query: "экономика", suggestedQuery: "", documentsFound: 0},
+	}
+
+	for _, testCase := range testCases {
+		searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+			Query:    testCase.query,
+			PageSize: 10,
+		})
+		require.NoError(t, err, testCase.query)
+		require.Equal(t, testCase.suggestedQuery, searchResponse.SuggestedQuery, testCase.query)
+		require.Equal(t, testCase.documentsFound, searchResponse.DocumentsFound, testCase.query)
+		require.False(t, searchResponse.AutoCorrected, testCase.query)
+	}
+}
+
+func Test_Find_Spelling_AutoCorrect(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(spellingTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Query:       "политка",
+		PageSize:    10,
+		AutoCorrect: true,
+	})
+	require.NoError(t, err)
+	require.True(t, searchResponse.AutoCorrected)
+	require.Equal(t, "политика", searchResponse.SuggestedQuery)
+	require.Equal(t, int64(2), searchResponse.DocumentsFound)
+
+	// Documents found by original query are kept even if there are few of them
+	searchResponse, err = service.Find(&indexService.SearchDocumentRequest{
+		Query:       "мировая политка",
+		PageSize:    10,
+		AutoCorrect: true,
+	})
+	require.NoError(t, err)
+	require.False(t, searchResponse.AutoCorrected)
+	require.Equal(t, "мировая политика", searchResponse.SuggestedQuery)
+	require.Equal(t, int64(1), searchResponse.DocumentsFound)
+}
@@ -0,0 +1,128 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/stretchr/testify/require"
+)
+
+var suggestTestDocuments = []models.DocumentResponse{
+	{
+		ID:   1,
+		Name: "Футбольный клуб выиграл кубок",
+		Body: "Клуб из Москвы выиграл кубок страны.",
+		Tags: []models.TagResponse{{ID: 1, Name: "Спорт"}, {ID: 2, Name: "Футбол"}},
+	},
+	{
+		ID:   2,
+		Name: "Футбол",
+		Body: "Новости футбола.",
+		Tags: []models.TagResponse{{ID: 1, Name: "Спорт"}, {ID: 2, Name: "Футбол"}},
+	},
+	{
+		ID:   3,
+		Name: "Футзал в Москве",
+		Body: "Турнир по мини-футболу.",
+		Tags: []models.TagResponse{{ID: 1, Name: "Спорт"}, {ID: 3, Name: "Футзал"}},
+	},
+	{
+		ID:   4,
+		Name: "Курс рубля",
+		Body: "Центральный банк опубликовал новый курс рубля.",
+		Tags: []models.TagResponse{{ID: 4, Name: "Экономика"}},
+	},
+}
+
+func Test_Suggest(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(suggestTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		prefix            string
+		expectedDocuments []models.ID
+		expectedTags      []indexService.TagSuggestion
+	}{
+		{
+			prefix:            "фут",
+			expectedDocuments: []models.ID{2, 3, 1},
+			expectedTags: []indexService.TagSuggestion{
+				{ID: 2, Name: "Футбол", DocumentCount: 2},
+				{ID: 3, Name: "Футзал", DocumentCount: 1},
+			},
+		},
+		{
+			prefix:            "Футбольный кл",
+			expectedDocuments: []models.ID{1},
+			expectedTags:      []indexService.TagSuggestion{},
+		},
+		{
+			prefix:            "москв",
+			expectedDocuments: []models.ID{3},
+			expectedTags:      []indexService.TagSuggestion{},
+		},
+		{
+			prefix:            "с",
+			expectedDocuments: []models.ID{},
+			expectedTags:      []indexService.TagSuggestion{{ID: 1, Name: "Спорт", DocumentCount: 3}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		suggestResponse, err := service.Suggest(&indexService.SuggestRequest{Prefix: testCase.prefix})
+		require.NoError(t, err, testCase.prefix)
+
+		actualDocuments := make([]models.ID, 0, len(suggestResponse.Documents))
+		for _, document := range suggestResponse.Documents {
+			actualDocuments = append(actualDocuments, document.ID)
+		}
+		require.Equal(t, testCase.expectedDocuments, actualDocuments, testCase.prefix)
+		require.Equal(t, testCase.expectedTags, suggestResponse.Tags, testCase.prefix)
+	}
+}
+
+func Test_Suggest_Size(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(suggestTestDocuments)
+	defer cleanupFunc()
+
+	suggestResponse, err := service.Suggest(&indexService.SuggestRequest{Prefix: "фут", Size: 1})
+	require.NoError(t, err)
+	require.Equal(t, []indexService.DocumentSuggestion{{ID: 2, Name: "Футбол"}}, suggestResponse.Documents)
+	require.Equal(t, []indexService.TagSuggestion{{ID: 2, Name: "Футбол", DocumentCount: 2}}, suggestResponse.Tags)
+}
+
+// Tags are suggested from index only, so tags unknown to repository are still suggested
+func Test_Suggest_Tags_From_Index(t *testing.T) {
+	index, err := bleve.NewMemOnly(indexService.GetIndexMapping())
+	require.NoError(t, err)
+	defer index.Close()
+
+	service := indexService.NewIndexService(index, NewMockDocumentRepository(suggestTestDocuments), NewMockTagRepository(nil))
+	require.NoError(t, service.Index(suggestTestDocuments))
+
+	suggestResponse, err := service.Suggest(&indexService.SuggestRequest{Prefix: "фут"})
+	require.NoError(t, err)
+	require.Equal(t, []indexService.TagSuggestion{
+		{ID: 2, Name: "Футбол", DocumentCount: 2},
+		{ID: 3, Name: "Футзал", DocumentCount: 1},
+	}, suggestResponse.Tags)
+}
+
+func Test_Suggest_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(suggestTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []indexService.SuggestRequest{
+		{Prefix: ""},
+		{Prefix: "  "},
+		{Prefix: "фут", Size: -1},
+		{Prefix: "фут", Size: indexService.MaxSuggestSize + 1},
+	}
+
+	for _, suggestRequest := range testCases {
+		_, err := service.Suggest(&suggestRequest)
+		require.ErrorIs(t, err, indexService.ErrInvalidSuggestRequest)
+	}
+}
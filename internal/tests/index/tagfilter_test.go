@@ -0,0 +1,140 @@
+package utilities
+
+import (
+	"slices"
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+var tagFilterTestDocuments = []models.DocumentResponse{
+	{
+		ID:   1,
+		Name: "Выборы",
+		Body: "документ о политике",
+		Tags: []models.TagResponse{{ID: 1, Name: "политика", Assigned: true}},
+	},
+	{
+		ID:   2,
+		Name: "Бюджет",
+		Body: "документ о политике и экономике",
+		Tags: []models.TagResponse{{ID: 1, Name: "политика", Assigned: true}, {ID: 2, Name: "экономика", Assigned: true}},
+	},
+	{
+		ID:   3,
+		Name: "Спонсоры клуба",
+		Body: "документ об экономике в спорте",
+		Tags: []models.TagResponse{{ID: 2, Name: "экономика", Assigned: true}, {ID: 3, Name: "спорт", Assigned: true}},
+	},
+	{
+		ID:   4,
+		Name: "Матч",
+		Body: "документ о спорте",
+		Tags: []models.TagResponse{{ID: 3, Name: "спорт", Assigned: true}, {ID: 4, Name: "c&c", Assigned: true}},
+	},
+}
+
+func Test_Find_TagFilter(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(tagFilterTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []struct {
+		filter   string
+		expected []models.ID
+	}{
+		{filter: "политика", expected: []models.ID{1, 2}},
+		{filter: "политика & экономика", expected: []models.ID{2}},
+		{filter: "политика | экономика", expected: []models.ID{1, 2, 3}},
+		{filter: "(политика | экономика) & !спорт", expected: []models.ID{1, 2}},
+		{filter: "!экономика", expected: []models.ID{1, 4}},
+		{filter: "!!спорт", expected: []models.ID{3, 4}},
+		{filter: `"c&c"`, expected: []models.ID{4}},
+		{filter: `спорт & !"c&c"`, expected: []models.ID{3}},
+		{filter: "!(политика | спорт)", expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+			TagFilter: testCase.filter,
+			PageSize:  10,
+			Sort:      []string{"id"},
+		})
+		require.NoError(t, err, "filter '%s'", testCase.filter)
+
+		var actual []models.ID
+		for _, document := range searchResponse.Documents {
+			actual = append(actual, document.ID)
+		}
+		require.Equal(t, testCase.expected, actual, "filter '%s'", testCase.filter)
+	}
+}
+
+func Test_Find_TagFilter_Buckets(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(tagFilterTestDocuments)
+	defer cleanupFunc()
+
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		TagFilter: "(политика | экономика) & !спорт",
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+
+	bucketIndex := slices.IndexFunc(searchResponse.Tags, func(bucket indexService.TagBucket) bool { return bucket.Name == "политика" })
+	require.NotEqual(t, -1, bucketIndex)
+	require.True(t, searchResponse.Tags[bucketIndex].Selected)
+	require.False(t, searchResponse.Tags[bucketIndex].Excluded)
+
+	bucketIndex = slices.IndexFunc(searchResponse.Tags, func(bucket indexService.TagBucket) bool { return bucket.Name == "спорт" })
+	require.NotEqual(t, -1, bucketIndex)
+	require.False(t, searchResponse.Tags[bucketIndex].Selected)
+	require.True(t, searchResponse.Tags[bucketIndex].Excluded)
+}
+
+func Test_Find_TagFilter_Invalid(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(tagFilterTestDocuments)
+	defer cleanupFunc()
+
+	testCases := []string{
+		"политика &",
+		"| политика",
+		"(политика | экономика",
+		"политика)",
+		`"политика`,
+		`""`,
+		"политика & & спорт",
+		"!",
+	}
+
+	for _, filter := range testCases {
+		_, err := service.Find(&indexService.SearchDocumentRequest{
+			TagFilter: filter,
+			PageSize:  10,
+		})
+		require.ErrorIs(t, err, indexService.ErrInvalidTagFilter, "filter '%s'", filter)
+	}
+}
+
+func Test_Find_TagFilter_Contradicts_Tags(t *testing.T) {
+	service, cleanupFunc := NewTestMemIndexService(tagFilterTestDocuments)
+	defer cleanupFunc()
+
+	_, err := service.Find(&indexService.SearchDocumentRequest{
+		Tags:      []string{"политика"},
+		TagFilter: "экономика | !политика",
+		PageSize:  10,
+	})
+	require.ErrorIs(t, err, indexService.ErrInvalidTagFilter)
+
+	// Tag which is only negated in filter is excluded and not selected
+	searchResponse, err := service.Find(&indexService.SearchDocumentRequest{
+		Tags:      []string{"экономика"},
+		TagFilter: "!политика",
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	for _, bucket := range searchResponse.Tags {
+		require.False(t, bucket.Selected && bucket.Excluded, "tag '%s'", bucket.Name)
+	}
+}
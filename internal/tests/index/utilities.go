@@ -157,10 +157,12 @@ func NewMockDocumentRepository(documents []models.DocumentResponse) *MockDocumen
 	store := map[models.ID]models.DocumentResponse{}
 	for _, document := range documents {
 		store[int64(document.ID)] = models.DocumentResponse{
-			ID:   int64(document.ID),
-			Name: document.Name,
-			Body: document.Body,
-			Tags: document.Tags,
+			ID:        int64(document.ID),
+			Name:      document.Name,
+			Body:      document.Body,
+			Tags:      document.Tags,
+			CreatedAt: document.CreatedAt,
+			UpdatedAt: document.UpdatedAt,
 		}
 	}
 	return &MockDocumentRepository{
@@ -208,6 +210,11 @@ func (repository *MockTagRepository) ReadManyByNames(names []string) (response [
 	return response, nil
 }
 
+// Mock repository stores tags without aliases so nothing is resolved
+func (repository *MockTagRepository) ResolveAliases(names []string) (response map[string]models.TagResponse, err error) {
+	return map[string]models.TagResponse{}, nil
+}
+
 func (repository *MockDocumentRepository) ReadMany(IDs []models.ID) (response []models.DocumentResponse, err error) {
 	for _, id := range IDs {
 		if document, ok := repository.store[id]; ok {
@@ -217,6 +224,14 @@ func (repository *MockDocumentRepository) ReadMany(IDs []models.ID) (response []
 	return response, nil
 }
 
+func (repository *MockDocumentRepository) ListIDs() (response []models.ID, err error) {
+	for id := range repository.store {
+		response = append(response, id)
+	}
+	slices.Sort(response)
+	return response, nil
+}
+
 func getTestIndex() (index bleve.Index, indexTestData bool) {
 	const testIndexName = "test_index.bleve"
 
@@ -258,3 +273,24 @@ func NewTestIndexService(testDataPath string) (*service.IndexService, []models.D
 		testData,
 		func() { index.Close() }
 }
+
+// Returns index service backed by in-memory index with passed documents indexed.
+// Useful for tests which don't need whole dataset.
+func NewTestMemIndexService(documents []models.DocumentResponse) (*service.IndexService, func()) {
+	index, err := bleve.NewMemOnly(service.GetIndexMapping())
+	if err != nil {
+		panic(err)
+	}
+
+	indexService := service.NewIndexService(
+		index,
+		NewMockDocumentRepository(documents),
+		NewMockTagRepository(documents),
+	)
+
+	if err := indexService.Index(documents); err != nil {
+		panic(err)
+	}
+
+	return indexService, func() { index.Close() }
+}
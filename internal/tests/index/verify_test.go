@@ -0,0 +1,52 @@
+package utilities
+
+import (
+	"testing"
+
+	indexService "github.com/Wayodeni/tagsearch-backend/internal/service/index"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Verify(t *testing.T) {
+	politics := models.TagResponse{ID: 1, Name: "политика", Assigned: true}
+	economy := models.TagResponse{ID: 2, Name: "экономика", Assigned: true}
+
+	indexedDocuments := []models.DocumentResponse{
+		{ID: 1, Name: "Выборы", Body: "Итоги выборов", Tags: []models.TagResponse{politics, economy}},
+		{ID: 2, Name: "Бюджет", Body: "Проект бюджета", Tags: []models.TagResponse{economy}},
+		{ID: 3, Name: "Удалённый", Body: "Удалённый документ"},
+	}
+	databaseDocuments := []models.DocumentResponse{
+		{ID: 1, Name: "Выборы", Body: "Итоги выборов", Tags: []models.TagResponse{economy, politics}},
+		{ID: 2, Name: "Бюджет", Body: "Принятый бюджет", Tags: []models.TagResponse{politics}},
+		{ID: 4, Name: "Новый", Body: "Неиндексированный документ"},
+	}
+
+	service, cleanupFunc := NewTestMemIndexService(indexedDocuments)
+	defer cleanupFunc()
+	documentRepository := NewMockDocumentRepository(databaseDocuments)
+
+	expected := indexService.VerifyReport{
+		DatabaseDocuments: 3,
+		IndexDocuments:    3,
+		Missing:           []models.ID{4},
+		Orphaned:          []models.ID{3},
+		Stale:             []indexService.StaleDocument{{ID: 2, Fields: []string{"body", "tags"}}},
+	}
+
+	actual, err := service.Verify(documentRepository, false)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.False(t, actual.Consistent())
+
+	expected.Repaired = true
+	actual, err = service.Verify(documentRepository, true)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual, "report of repair must describe state before repair")
+
+	actual, err = service.Verify(documentRepository, false)
+	require.NoError(t, err)
+	require.True(t, actual.Consistent(), "index must be consistent after repair")
+	require.Equal(t, 3, actual.IndexDocuments)
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Wayodeni/tagsearch-backend/internal/service/outbox"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/db"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/models"
+	"github.com/Wayodeni/tagsearch-backend/internal/storage/repository"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+type recordingIndexer struct {
+	indexed map[models.ID]models.DocumentResponse
+	deleted []models.ID
+	err     error
+}
+
+func (indexer *recordingIndexer) Index(documents []models.DocumentResponse) error {
+	if indexer.err != nil {
+		return indexer.err
+	}
+	for _, document := range documents {
+		indexer.indexed[document.ID] = document
+	}
+	return nil
+}
+
+func (indexer *recordingIndexer) Delete(IDs []models.ID) error {
+	if indexer.err != nil {
+		return indexer.err
+	}
+	for _, id := range IDs {
+		delete(indexer.indexed, id)
+	}
+	indexer.deleted = append(indexer.deleted, IDs...)
+	return nil
+}
+
+func newTestWorker() (*outbox.Worker, *repository.DocumentRepository, *repository.OutboxRepository, *recordingIndexer, func()) {
+	db := db.NewDb(":memory:")
+	documentRepository := repository.NewDocumentRepository(db, repository.NewTagRepository(db))
+	outboxRepository := repository.NewOutboxRepository(db)
+	indexer := &recordingIndexer{indexed: map[models.ID]models.DocumentResponse{}}
+
+	return outbox.NewWorker(outboxRepository, documentRepository, indexer), documentRepository, outboxRepository, indexer, func() { db.Close() }
+}
+
+func Test_Worker_Syncs_Index(t *testing.T) {
+	worker, documentRepository, outboxRepository, indexer, cleanupFunc := newTestWorker()
+	defer cleanupFunc()
+
+	kept, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "kept", Body: "body"})
+	deleted, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "deleted", Body: "body"})
+	documentRepository.Update(kept.ID, models.UpdateDocumentRequest{Body: null.StringFrom("new body")})
+	documentRepository.Delete(deleted.ID)
+
+	require.NoError(t, worker.Drain())
+
+	require.Equal(t, "new body", indexer.indexed[kept.ID].Body, "latest document state must be indexed")
+	require.NotContains(t, indexer.indexed, deleted.ID)
+	require.Equal(t, []models.ID{deleted.ID}, indexer.deleted)
+
+	entries, err := outboxRepository.Fetch(10, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, entries, "processed entries must be removed")
+}
+
+func Test_Worker_Retries_Failed_Entries(t *testing.T) {
+	worker, documentRepository, outboxRepository, indexer, cleanupFunc := newTestWorker()
+	defer cleanupFunc()
+
+	document, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "name", Body: "body"})
+
+	indexer.err = errors.New("index is unavailable")
+	require.ErrorIs(t, worker.Drain(), indexer.err)
+
+	entries, err := outboxRepository.Fetch(10, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, entries, "failed entry must be postponed")
+
+	entries, err = outboxRepository.Fetch(10, time.Now().Add(outbox.DefaultRetryDelay))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, 1, entries[0].Attempts)
+	require.Equal(t, null.StringFrom("index is unavailable"), entries[0].LastError)
+
+	// Entry becomes available again, this time indexing succeeds
+	entries[0].AvailableAt = time.Now().UnixMilli()
+	require.NoError(t, outboxRepository.Fail(entries))
+	indexer.err = nil
+	require.NoError(t, worker.Drain())
+	require.Contains(t, indexer.indexed, document.ID)
+}
+
+func Test_Worker_Marks_Entries_Dead(t *testing.T) {
+	worker, documentRepository, outboxRepository, indexer, cleanupFunc := newTestWorker()
+	defer cleanupFunc()
+	worker.MaxAttempts = 2
+
+	document, _ := documentRepository.Create(models.CreateDocumentRequest{Name: "name", Body: "body"})
+
+	indexer.err = errors.New("document can not be indexed")
+	for attempt := 1; attempt <= worker.MaxAttempts; attempt++ {
+		require.ErrorIs(t, worker.Drain(), indexer.err)
+		entries, err := outboxRepository.Fetch(10, time.Now().Add(outbox.MaxRetryDelay))
+		require.NoError(t, err)
+		if attempt < worker.MaxAttempts {
+			require.Len(t, entries, 1)
+			entries[0].AvailableAt = time.Now().UnixMilli()
+			require.NoError(t, outboxRepository.Fail(entries))
+		} else {
+			require.Empty(t, entries, "dead entry must not be retried")
+		}
+	}
+
+	deadEntries, err := outboxRepository.ListDead()
+	require.NoError(t, err)
+	require.Len(t, deadEntries, 1)
+	require.Equal(t, document.ID, deadEntries[0].DocumentID)
+	require.Equal(t, worker.MaxAttempts, deadEntries[0].Attempts)
+	require.True(t, deadEntries[0].Dead)
+
+	// Revived entry is processed again from the first attempt
+	require.NoError(t, outboxRepository.Revive())
+	deadEntries, err = outboxRepository.ListDead()
+	require.NoError(t, err)
+	require.Empty(t, deadEntries)
+
+	indexer.err = nil
+	require.NoError(t, worker.Drain())
+	require.Contains(t, indexer.indexed, document.ID)
+}